@@ -2,7 +2,10 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,75 +16,249 @@ import (
 	"github.com/csawai/git-identity-switcher/internal/ssh"
 	"github.com/csawai/git-identity-switcher/internal/ui"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-var dryRun bool
+var (
+	dryRun         bool
+	addKeyPath     string
+	addAlias       string
+	addName        string
+	addEmail       string
+	addGitHubUser  string
+	addAuth        string
+	addGenerateKey bool
+	addPATStdin    bool
+	addFromJSON    string
+	addEmails      []string
+	addGitConfig   []string
+)
 
 func init() {
 	addIdentityCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without making changes")
+	addIdentityCmd.Flags().StringVar(&addKeyPath, "key", "", "Use an existing SSH private key instead of generating one")
+	addIdentityCmd.Flags().StringVar(&addAlias, "alias", "", "Identity alias (e.g. 'work')")
+	addIdentityCmd.Flags().StringVar(&addName, "name", "", "git user.name")
+	addIdentityCmd.Flags().StringVar(&addEmail, "email", "", "git user.email")
+	addIdentityCmd.Flags().StringArrayVar(&addEmails, "add-email", nil, "Another email the identity commits with (repeatable)")
+	addIdentityCmd.Flags().StringArrayVar(&addGitConfig, "git-config", nil, "Git config key=value that bind sets (repeatable)")
+	addIdentityCmd.Flags().StringVar(&addGitHubUser, "github-user", "", "GitHub username")
+	addIdentityCmd.Flags().StringVar(&addAuth, "auth", "", "Auth method: ssh or pat (default ssh)")
+	addIdentityCmd.Flags().BoolVar(&addGenerateKey, "generate-key", false, "Generate an SSH key (--generate-key=false for none)")
+	addIdentityCmd.Flags().BoolVar(&addPATStdin, "pat-stdin", false, "Read the personal access token from stdin")
+	addIdentityCmd.Flags().StringVar(&addFromJSON, "from-json", "", "Read the identity from a JSON document ('-' for stdin)")
+	addPATInfoFlags(addIdentityCmd)
 }
 
 var addIdentityCmd = &cobra.Command{
 	Use:   "add identity",
 	Short: "Add a new identity",
-	Long:  "Add a new GitHub identity with name, email, and GitHub username.",
+	Long: `Add a new GitHub identity with name, email, and GitHub username.
+
+Fields missing from the flags and --from-json are prompted for. When stdin is not a
+terminal nothing is prompted: every required field has to be given, ssh identities
+need --generate-key, --generate-key=false or --key, and pat identities --pat-stdin
+(a key can't be given for a pat identity).
+--from-json reads the alias, name, email, emails, github_user, auth_method, ssh_key_path,
+pat and git_config fields of an identity as stored in identities.json; flags override them.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := addIdentity(); err != nil {
+		if err := addIdentity(cmd); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
-func addIdentity() error {
-	reader := bufio.NewReader(os.Stdin)
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
 
-	fmt.Println(ui.HeaderStyle.Render("🔐 Add New Identity"))
-	fmt.Println(ui.SectionDivider())
-	fmt.Println()
+// identityFromInput builds the identity given by --from-json and the field flags
+func identityFromInput(cmd *cobra.Command) (config.Identity, error) {
+	var identity config.Identity
+	if addFromJSON != "" {
+		var data []byte
+		var err error
+		if addFromJSON == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(addFromJSON)
+		}
+		if err != nil {
+			return identity, fmt.Errorf("failed to read %s: %w", addFromJSON, err)
+		}
+		var doc config.Identity
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&doc); err != nil {
+			return identity, fmt.Errorf("invalid identity document: %w", err)
+		}
+		identity = config.Identity{
+			Alias:      doc.Alias,
+			Name:       doc.Name,
+			Email:      doc.Email,
+			GitHubUser: doc.GitHubUser,
+			AuthMethod: doc.AuthMethod,
+			SSHKeyPath: doc.SSHKeyPath,
+			PAT:        doc.PAT,
+			Emails:     doc.Emails,
+			GitConfig:  doc.GitConfig,
+		}
+	}
 
-	fmt.Print(ui.InfoText.Render("📝 Identity alias") + " (e.g., 'work', 'personal'): ")
-	alias, _ := reader.ReadString('\n')
-	alias = strings.TrimSpace(alias)
-	if alias == "" {
-		return fmt.Errorf("alias cannot be empty")
+	flags := cmd.Flags()
+	for flag, field := range map[string]*string{
+		"alias":       &identity.Alias,
+		"name":        &identity.Name,
+		"email":       &identity.Email,
+		"github-user": &identity.GitHubUser,
+		"auth":        &identity.AuthMethod,
+		"key":         &identity.SSHKeyPath,
+	} {
+		if flags.Changed(flag) {
+			value, _ := flags.GetString(flag)
+			*field = value
+		}
 	}
+	for _, field := range []*string{&identity.Alias, &identity.Name, &identity.Email, &identity.GitHubUser, &identity.AuthMethod, &identity.SSHKeyPath} {
+		*field = strings.TrimSpace(*field)
+	}
+	identity.AuthMethod = strings.ToLower(identity.AuthMethod)
+	identity.Emails = editEmails(identity.Emails, addEmails, []string{identity.Email})
+	gitConfig, err := withGitConfig(identity.GitConfig, addGitConfig, nil)
+	if err != nil {
+		return identity, err
+	}
+	identity.GitConfig = gitConfig
+	return identity, nil
+}
 
-	fmt.Print(ui.InfoText.Render("👤 Name") + ": ")
-	name, _ := reader.ReadString('\n')
-	name = strings.TrimSpace(name)
-	if name == "" {
-		return fmt.Errorf("name cannot be empty")
+func addIdentity(cmd *cobra.Command) error {
+	reader := bufio.NewReader(os.Stdin)
+	if addFromJSON == "-" && addPATStdin {
+		return fmt.Errorf("--from-json - and --pat-stdin both read stdin; pass the document as a file")
 	}
+	interactive := stdinIsTerminal() && addFromJSON != "-" && !addPATStdin
 
-	fmt.Print(ui.InfoText.Render("📧 Email") + ": ")
-	email, _ := reader.ReadString('\n')
-	email = strings.TrimSpace(email)
-	if email == "" {
-		return fmt.Errorf("email cannot be empty")
+	identity, err := identityFromInput(cmd)
+	if err != nil {
+		return err
 	}
 
-	fmt.Print(ui.InfoText.Render("🐙 GitHub username") + ": ")
-	githubUser, _ := reader.ReadString('\n')
-	githubUser = strings.TrimSpace(githubUser)
-	if githubUser == "" {
-		return fmt.Errorf("GitHub username cannot be empty")
+	if interactive {
+		fmt.Println(ui.HeaderStyle.Render("🔐 Add New Identity"))
+		fmt.Println(ui.SectionDivider())
+		fmt.Println()
+
+		prompts := []struct {
+			field *string
+			label string
+		}{
+			{&identity.Alias, ui.InfoText.Render("📝 Identity alias") + " (e.g., 'work', 'personal')"},
+			{&identity.Name, ui.InfoText.Render("👤 Name")},
+			{&identity.Email, ui.InfoText.Render("📧 Email")},
+			{&identity.GitHubUser, ui.InfoText.Render("🐙 GitHub username")},
+		}
+		for _, prompt := range prompts {
+			if *prompt.field == "" {
+				fmt.Print(prompt.label + ": ")
+				value, _ := reader.ReadString('\n')
+				*prompt.field = strings.TrimSpace(value)
+			}
+		}
+		if identity.AuthMethod == "" {
+			fmt.Print(ui.InfoText.Render("🔐 Auth method") + " (ssh/pat) [ssh]: ")
+			value, _ := reader.ReadString('\n')
+			identity.AuthMethod = strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	if identity.AuthMethod == "" {
+		identity.AuthMethod = "ssh"
 	}
 
-	// Ask for auth method
-	fmt.Print(ui.InfoText.Render("🔐 Auth method") + " (ssh/pat) [ssh]: ")
-	authMethod, _ := reader.ReadString('\n')
-	authMethod = strings.TrimSpace(authMethod)
-	if authMethod == "" {
-		authMethod = "ssh"
+	var missing []string
+	for _, field := range []struct{ value, flag string }{
+		{identity.Alias, "--alias"},
+		{identity.Name, "--name"},
+		{identity.Email, "--email"},
+		{identity.GitHubUser, "--github-user"},
+	} {
+		if field.value == "" {
+			missing = append(missing, field.flag)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	if identity.AuthMethod == "pat" {
+		if identity.SSHKeyPath != "" {
+			return fmt.Errorf("--key (or ssh_key_path) only applies to ssh identities")
+		}
+		if addGenerateKey {
+			return fmt.Errorf("--generate-key only applies to ssh identities")
+		}
 	}
 
-	identity := config.Identity{
-		Alias:      alias,
-		Name:       name,
-		Email:      email,
-		GitHubUser: githubUser,
-		AuthMethod: authMethod,
+	alias, name, email, githubUser, authMethod := identity.Alias, identity.Name, identity.Email, identity.GitHubUser, identity.AuthMethod
+	addKeyPath = identity.SSHKeyPath
+	identity.SSHKeyPath = ""
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+	// Checked again by config.AddIdentity, but an existing identity's SSH entry
+	// and keychain PAT must not be overwritten on the way there
+	if _, err := config.FindIdentityByAlias(alias); err == nil {
+		return fmt.Errorf("identity with alias '%s' already exists", alias)
+	}
+
+	// Decide up front how the SSH key and PAT are obtained, so a script gets
+	// an error rather than a prompt it can't answer
+	generateKey := addGenerateKey
+	if authMethod == "ssh" && addKeyPath == "" && !cmd.Flags().Changed("generate-key") {
+		if !interactive {
+			return fmt.Errorf("stdin is not a terminal: pass --generate-key, --generate-key=false or --key")
+		}
+		fmt.Print("Generate SSH key? (y/n) [y]: ")
+		generate, _ := reader.ReadString('\n')
+		generate = strings.TrimSpace(strings.ToLower(generate))
+		generateKey = generate == "" || generate == "y"
+	}
+	var token string
+	if authMethod == "pat" {
+		switch {
+		case addPATStdin && dryRun:
+			// A dry run doesn't take secrets; the token is left unread
+		case addPATStdin:
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return fmt.Errorf("failed to read PAT from stdin: %w", err)
+			}
+			if token = strings.TrimSpace(string(data)); token == "" {
+				return fmt.Errorf("PAT cannot be empty")
+			}
+		case !interactive:
+			return fmt.Errorf("stdin is not a terminal: pass the token with --pat-stdin")
+		}
+	}
+	storePAT := func(store keychain.SecretStore) error {
+		if token == "" {
+			return readAndStorePAT(reader, store, alias)
+		}
+		if err := store.Store(alias, "pat", token); err != nil {
+			return fmt.Errorf("failed to store PAT: %w", err)
+		}
+		return nil
+	}
+	patInfo := func() (*config.PATInfo, error) {
+		flags := cmd.Flags()
+		if !interactive && !flags.Changed("expires") && !flags.Changed("scopes") && !flags.Changed("note") {
+			if identity.PAT == nil {
+				return &config.PATInfo{}, nil
+			}
+			return identity.PAT, nil
+		}
+		return patInfoFromInput(cmd, reader, identity.PAT)
 	}
 
 	if dryRun {
@@ -89,17 +266,71 @@ func addIdentity() error {
 		fmt.Printf("  Alias: %s\n", alias)
 		fmt.Printf("  Name: %s\n", name)
 		fmt.Printf("  Email: %s\n", email)
+		if len(identity.Emails) > 0 {
+			fmt.Printf("  Other emails: %s\n", strings.Join(identity.Emails, ", "))
+		}
+		for _, key := range sortedKeys(identity.GitConfig) {
+			fmt.Printf("  Git config: %s=%s\n", key, identity.GitConfig[key])
+		}
 		fmt.Printf("  GitHub: %s\n", githubUser)
 		fmt.Printf("  Auth: %s\n", authMethod)
+		if addKeyPath != "" {
+			fmt.Printf("  SSH key: %s (existing)\n", addKeyPath)
+		} else if authMethod == "ssh" && generateKey {
+			fmt.Println("  SSH key: generate")
+		}
+		if authMethod == "pat" {
+			source := "a prompt"
+			if addPATStdin {
+				source = "stdin"
+			}
+			fmt.Printf("  PAT: read from %s and stored in the keychain\n", source)
+			// Only the details already given are shown; a dry run doesn't prompt
+			info := identity.PAT
+			if flags := cmd.Flags(); flags.Changed("expires") || flags.Changed("scopes") || flags.Changed("note") {
+				if info, err = patInfoFromInput(cmd, reader, identity.PAT); err != nil {
+					return err
+				}
+			}
+			if info != nil {
+				fmt.Println(describePAT(info))
+			}
+		}
 		return nil
 	}
 
-		// Handle SSH key generation
 	if authMethod == "ssh" {
-		fmt.Print("Generate SSH key? (y/n) [y]: ")
-		generate, _ := reader.ReadString('\n')
-		generate = strings.TrimSpace(strings.ToLower(generate))
-		if generate == "" || generate == "y" {
+		if err := ensureKnownHosts(); err != nil {
+			return fmt.Errorf("failed to seed known_hosts: %w", err)
+		}
+	}
+
+		// Handle SSH key generation
+	if authMethod == "ssh" && addKeyPath != "" {
+		keyPath, err := ssh.ExpandPath(addKeyPath)
+		if err != nil {
+			return err
+		}
+		if keyPath, err = filepath.Abs(keyPath); err != nil {
+			return err
+		}
+		if _, err := os.Stat(keyPath); err != nil {
+			return fmt.Errorf("SSH key not found: %s", keyPath)
+		}
+		if _, err := os.Stat(keyPath + ".pub"); err != nil {
+			return fmt.Errorf("SSH public key not found: %s.pub", keyPath)
+		}
+
+		identity.SSHKeyPath = keyPath
+		identity.SSHHostAlias = fmt.Sprintf("github.com-%s", alias)
+		if err := ui.SpinnerWithFunc("Updating SSH config", func() error {
+			return ssh.AddSSHConfigEntry(identity.SSHHostAlias, keyPath)
+		}); err != nil {
+			return fmt.Errorf("failed to add SSH config: %w", err)
+		}
+		fmt.Println(ui.SuccessText.Render("✓ Using existing SSH key: " + keyPath))
+	} else if authMethod == "ssh" {
+		if generateKey {
 			// Backup SSH config first
 			backupPath, err := ssh.BackupSSHConfig()
 			if err != nil {
@@ -135,18 +366,15 @@ func addIdentity() error {
 			showSSHKeyInstructions(alias, keyPath)
 		}
 	} else if authMethod == "pat" {
-		fmt.Print("Personal Access Token: ")
-		token, _ := reader.ReadString('\n')
-		token = strings.TrimSpace(token)
-		if token == "" {
-			return fmt.Errorf("PAT cannot be empty")
-		}
-
-		// Store PAT in keychain
-		if err := keychain.StoreSecret(alias, "pat", token); err != nil {
-			return fmt.Errorf("failed to store PAT: %w", err)
+		if err := storePAT(secretStore); err != nil {
+			return err
 		}
 		fmt.Println("✓ PAT stored securely in keychain")
+		info, err := patInfo()
+		if err != nil {
+			return err
+		}
+		identity.PAT = info
 	}
 
 	if err := config.AddIdentity(identity); err != nil {
@@ -211,6 +439,9 @@ func showSSHKeyInstructions(alias, keyPath string) {
 	fmt.Println()
 
 	// Offer to copy to clipboard
+	if !stdinIsTerminal() {
+		return
+	}
 	fmt.Print("Copy to clipboard now? (y/n) [y]: ")
 	reader := bufio.NewReader(os.Stdin)
 	response, _ := reader.ReadString('\n')
@@ -257,3 +488,26 @@ func copyToClipboard(data []byte) error {
 
 	return copyCmd.Wait()
 }
+
+// readPAT prompts for a personal access token
+func readPAT(reader *bufio.Reader) (string, error) {
+	fmt.Print("Personal Access Token: ")
+	token, _ := reader.ReadString('\n')
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("PAT cannot be empty")
+	}
+	return token, nil
+}
+
+// readAndStorePAT prompts for a personal access token and saves it in store
+func readAndStorePAT(reader *bufio.Reader, store keychain.SecretStore, alias string) error {
+	token, err := readPAT(reader)
+	if err != nil {
+		return err
+	}
+	if err := store.Store(alias, "pat", token); err != nil {
+		return fmt.Errorf("failed to store PAT: %w", err)
+	}
+	return nil
+}
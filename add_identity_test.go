@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+func TestAddIdentityWithoutTerminal(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--alias", "work"}, "missing --name, --email, --github-user"},
+		{[]string{"--alias", "work", "--name", "Work", "--email", "work@example.com", "--github-user", "octo"}, "pass --generate-key, --generate-key=false or --key"},
+		{[]string{"--alias", "work", "--name", "Work", "--email", "work@example.com", "--github-user", "octo", "--auth", "pat"}, "pass the token with --pat-stdin"},
+		{[]string{"--alias", "work", "--name", "Work", "--email", "not-an-email", "--github-user", "octo", "--generate-key=false"}, `invalid email "not-an-email"`},
+		{[]string{"--alias", "work", "--name", "Work", "--email", "work@example.com", "--github-user", "octo", "--auth", "pat", "--pat-stdin", "--key", "/keys/id_work"}, "--key (or ssh_key_path) only applies to ssh identities"},
+		{[]string{"--alias", "work", "--name", "Work", "--email", "work@example.com", "--github-user", "octo", "--auth", "pat", "--pat-stdin", "--generate-key"}, "--generate-key only applies to ssh identities"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			usePATIdentities(t)
+			withStdin(t, "")
+			parseFlags(t, addIdentityCmd, tt.args...)
+			err := addIdentity(addIdentityCmd)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddIdentityFromJSON(t *testing.T) {
+	usePATIdentities(t)
+	withStdin(t, `{"alias": "work", "name": "Work", "email": "work@example.com", "emails": ["w@example.org"],
+		"github_user": "octo", "auth_method": "ssh", "git_config": {"pull.rebase": "true"}}`)
+	// Flags override the document
+	parseFlags(t, addIdentityCmd, "--from-json", "-", "--name", "Work Person", "--generate-key=false")
+
+	if err := addIdentity(addIdentityCmd); err != nil {
+		t.Fatal(err)
+	}
+	identity, err := config.FindIdentityByAlias("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Name != "Work Person" || identity.GitHubUser != "octo" || identity.AuthMethod != "ssh" || identity.SSHKeyPath != "" {
+		t.Errorf("identity = %+v", identity)
+	}
+	if strings.Join(identity.Emails, ",") != "w@example.org" || identity.GitConfig["pull.rebase"] != "true" {
+		t.Errorf("emails %v, git config %v", identity.Emails, identity.GitConfig)
+	}
+}
+
+func TestAddIdentityFromJSONKeyWithPAT(t *testing.T) {
+	usePATIdentities(t)
+	file := filepath.Join(t.TempDir(), "identity.json")
+	os.WriteFile(file, []byte(`{"alias": "work", "name": "Work", "email": "work@example.com", "github_user": "octo",
+		"auth_method": "pat", "ssh_key_path": "/keys/id_work"}`), 0600)
+	withStdin(t, "secret-token\n")
+	parseFlags(t, addIdentityCmd, "--from-json", file, "--pat-stdin")
+
+	if err := addIdentity(addIdentityCmd); err == nil || !strings.Contains(err.Error(), "only applies to ssh identities") {
+		t.Errorf("error = %v, want the key refused", err)
+	}
+	if _, err := config.FindIdentityByAlias("work"); err == nil {
+		t.Error("identity was added")
+	}
+}
+
+func TestIdentityFromJSONRejectsUnknownFields(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "identity.json")
+	os.WriteFile(file, []byte(`{"alias": "work", "ssh_host": "github.com-work"}`), 0600)
+	parseFlags(t, addIdentityCmd, "--from-json", file)
+
+	if _, err := identityFromInput(addIdentityCmd); err == nil || !strings.Contains(err.Error(), "invalid identity document") {
+		t.Errorf("error = %v, want an invalid document", err)
+	}
+}
+
+func TestAddIdentityPATStdin(t *testing.T) {
+	args := []string{"--alias", "work", "--name", "Work", "--email", "work@example.com", "--github-user", "octo", "--auth", "pat", "--pat-stdin", "--scopes", "repo"}
+
+	// A dry run leaves the token unread and stores nothing
+	store := usePATIdentities(t)
+	withStdin(t, "secret-token\n")
+	parseFlags(t, addIdentityCmd, append(args, "--dry-run")...)
+	if err := addIdentity(addIdentityCmd); err != nil {
+		t.Fatal(err)
+	}
+	if rest, _ := io.ReadAll(os.Stdin); string(rest) != "secret-token\n" {
+		t.Errorf("dry run read the token from stdin; left %q", rest)
+	}
+	if _, err := store.Get("work", "pat"); err == nil {
+		t.Error("dry run stored a PAT")
+	}
+	if _, err := config.FindIdentityByAlias("work"); err == nil {
+		t.Error("dry run added the identity")
+	}
+
+	withStdin(t, "secret-token\n")
+	parseFlags(t, addIdentityCmd, args...)
+	if err := addIdentity(addIdentityCmd); err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := store.Get("work", "pat"); token != "secret-token" {
+		t.Errorf("stored PAT = %q", token)
+	}
+	identity, _ := config.FindIdentityByAlias("work")
+	if identity.PAT == nil || strings.Join(identity.PAT.Scopes, ",") != "repo" {
+		t.Errorf("PAT details = %+v", identity.PAT)
+	}
+}
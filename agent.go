@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Manage per-identity ssh-agents",
+	Long: `Run a dedicated ssh-agent for an identity so its host alias only ever sees its own key.
+The agent socket lives in the gitx runtime dir and is written as IdentityAgent into the managed SSH config.`,
+}
+
+var agentEnableCmd = &cobra.Command{
+	Use:   "enable [alias]",
+	Short: "Use an isolated ssh-agent for an identity",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setIsolatedAgent(args[0], true); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentDisableCmd = &cobra.Command{
+	Use:   "disable [alias]",
+	Short: "Stop using an isolated ssh-agent for an identity",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setIsolatedAgent(args[0], false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentStartCmd = &cobra.Command{
+	Use:   "start [alias]",
+	Short: "Start isolated agents (all enabled identities if no alias given)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := startAgents(args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop [alias]",
+	Short: "Stop isolated agents (all if no alias given)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := stopAgents(args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show isolated agent status for each identity",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := agentStatus(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	agentCmd.AddCommand(agentEnableCmd)
+	agentCmd.AddCommand(agentDisableCmd)
+	agentCmd.AddCommand(agentStartCmd)
+	agentCmd.AddCommand(agentStopCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	rootCmd.AddCommand(agentCmd)
+}
+
+func identityAgentSocket(alias string) (string, error) {
+	runtimeDir, err := config.GetRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return ssh.AgentSocketPath(runtimeDir, alias), nil
+}
+
+// ensureIdentityAgent starts the identity's dedicated agent if needed and
+// points its managed SSH host entry at the agent socket
+func ensureIdentityAgent(identity *config.Identity) error {
+	socketPath, err := identityAgentSocket(identity.Alias)
+	if err != nil {
+		return err
+	}
+	if err := ssh.StartAgent(socketPath, identity.SSHKeyPath); err != nil {
+		return err
+	}
+	return ssh.SetIdentityAgent(identity.SSHHostAlias, socketPath)
+}
+
+func setIsolatedAgent(alias string, enabled bool) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	if identity.AuthMethod != "ssh" || identity.SSHKeyPath == "" || identity.SSHHostAlias == "" {
+		return fmt.Errorf("identity '%s' does not use an SSH key", alias)
+	}
+	if identity.ExternalHostEntry {
+		return fmt.Errorf("SSH host %s is not managed by gitx; re-import it with 'gitx import ssh --move' first", identity.SSHHostAlias)
+	}
+
+	socketPath, err := identityAgentSocket(alias)
+	if err != nil {
+		return err
+	}
+
+	if enabled {
+		// Make sure the host entry exists before pointing it at the agent
+		if err := ssh.AddSSHConfigEntry(identity.SSHHostAlias, identity.SSHKeyPath); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+		if err := ensureIdentityAgent(identity); err != nil {
+			return err
+		}
+	} else {
+		if err := ssh.SetIdentityAgent(identity.SSHHostAlias, ""); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+		if err := ssh.StopAgent(socketPath); err != nil {
+			return err
+		}
+	}
+
+	identity.IsolatedAgent = enabled
+	if err := config.UpdateIdentity(*identity); err != nil {
+		return err
+	}
+
+	if enabled {
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Isolated agent enabled for '%s'", alias)))
+		fmt.Printf("  Socket: %s\n", socketPath)
+	} else {
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Isolated agent disabled for '%s'", alias)))
+	}
+	return nil
+}
+
+// agentIdentities returns the identities with an isolated agent, limited to args[0] if given
+func agentIdentities(args []string) ([]config.Identity, error) {
+	if len(args) == 1 {
+		identity, err := config.FindIdentityByAlias(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if !identity.IsolatedAgent {
+			return nil, fmt.Errorf("identity '%s' does not use an isolated agent (run 'gitx agent enable %s')", args[0], args[0])
+		}
+		return []config.Identity{*identity}, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var identities []config.Identity
+	for _, id := range cfg.Identities {
+		if id.IsolatedAgent {
+			identities = append(identities, id)
+		}
+	}
+	return identities, nil
+}
+
+func startAgents(args []string) error {
+	identities, err := agentIdentities(args)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		fmt.Println("No identities use an isolated agent.")
+		return nil
+	}
+
+	for i := range identities {
+		if err := ensureIdentityAgent(&identities[i]); err != nil {
+			return fmt.Errorf("%s: %w", identities[i].Alias, err)
+		}
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Agent running for '%s'", identities[i].Alias)))
+	}
+	return nil
+}
+
+func stopAgents(args []string) error {
+	identities, err := agentIdentities(args)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range identities {
+		socketPath, err := identityAgentSocket(id.Alias)
+		if err != nil {
+			return err
+		}
+		if err := ssh.StopAgent(socketPath); err != nil {
+			return fmt.Errorf("%s: %w", id.Alias, err)
+		}
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Agent stopped for '%s'", id.Alias)))
+	}
+	return nil
+}
+
+func agentStatus() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range cfg.Identities {
+		if !id.IsolatedAgent {
+			fmt.Printf("%s %s: shared agent\n", ui.MutedText.Render("○"), id.Alias)
+			continue
+		}
+		socketPath, err := identityAgentSocket(id.Alias)
+		if err != nil {
+			return err
+		}
+		if ssh.AgentRunning(socketPath) {
+			fmt.Printf("%s %s: running (%s)\n", ui.StatusBound, id.Alias, socketPath)
+		} else {
+			fmt.Printf("%s %s: stopped (run 'gitx agent start %s')\n", ui.StatusUnbound, id.Alias, id.Alias)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var auditSecretsYes bool
+
+func init() {
+	auditSecretsCmd.Flags().BoolVar(&auditSecretsYes, "yes", false, "Move found credentials into the keyring without asking")
+	auditCmd.AddCommand(auditSecretsCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Check the current repository for identity problems",
+}
+
+var auditSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Find credentials embedded in the repository's git config",
+	Long: `Look for tokens in remote URLs, http.extraHeader and url.<base>.insteadOf entries of
+the repository's .git/config. When the repository is bound to a PAT identity, gitx
+offers to move the token into the keyring and strip it from the config; git then gets
+it from the gitx credential helper.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := auditSecrets(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// configSecret is a credential found in a git config entry
+type configSecret struct {
+	Key    string // config key, as printed by git config --list
+	Value  string
+	Kind   string // "remote URL", "extra header" or "insteadOf"
+	Token  string // the secret itself
+	Masked string // printable form of the entry with the secret masked
+}
+
+// tokenPrefixes identify well-known token formats used as a bare username
+var tokenPrefixes = []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_", "glpat-"}
+
+// maskSecret keeps a short prefix of s so it can be recognised without being usable
+func maskSecret(s string) string {
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return prefix + "****"
+		}
+	}
+	if len(s) > 12 {
+		return s[:3] + "****"
+	}
+	return "****"
+}
+
+// urlSecret returns the credential in a URL's userinfo and the URL with it masked.
+// A user:password pair yields the password; a bare username only counts when it
+// looks like a token.
+func urlSecret(raw string) (string, string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", ""
+	}
+	username := u.User.Username()
+	if password, ok := u.User.Password(); ok && password != "" {
+		masked := *u
+		masked.User = url.UserPassword(username, maskSecret(password))
+		return password, maskedURL(&masked)
+	}
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(username, prefix) {
+			masked := *u
+			masked.User = url.User(maskSecret(username))
+			return username, maskedURL(&masked)
+		}
+	}
+	return "", ""
+}
+
+// maskedURL renders u without escaping the mask's '*' characters
+func maskedURL(u *url.URL) string {
+	s, _ := url.PathUnescape(u.String())
+	return s
+}
+
+// stripUserinfo removes credentials from a URL
+func stripUserinfo(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+// headerSecret extracts the token from an "Authorization: ..." header value
+func headerSecret(header string) string {
+	name, value, ok := strings.Cut(header, ":")
+	if !ok || !strings.EqualFold(strings.TrimSpace(name), "authorization") {
+		return ""
+	}
+	scheme, credential, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return ""
+	}
+	credential = strings.TrimSpace(credential)
+	if strings.EqualFold(scheme, "basic") {
+		decoded, err := base64.StdEncoding.DecodeString(credential)
+		if err != nil {
+			return credential
+		}
+		if _, password, ok := strings.Cut(string(decoded), ":"); ok {
+			return password
+		}
+		return string(decoded)
+	}
+	return credential
+}
+
+// scanConfigSecrets looks for credentials in the output of 'git config -z --list'
+func scanConfigSecrets(list string) []configSecret {
+	var found []configSecret
+	for _, record := range strings.Split(list, "\x00") {
+		key, value, _ := strings.Cut(record, "\n")
+		lower := strings.ToLower(key)
+		switch {
+		case strings.HasPrefix(lower, "remote.") && (strings.HasSuffix(lower, ".url") || strings.HasSuffix(lower, ".pushurl")):
+			if token, masked := urlSecret(value); token != "" {
+				found = append(found, configSecret{Key: key, Value: value, Kind: "remote URL", Token: token, Masked: key + " = " + masked})
+			}
+		case strings.HasPrefix(lower, "http.") && strings.HasSuffix(lower, ".extraheader"):
+			if token := headerSecret(value); token != "" {
+				name, _, _ := strings.Cut(value, ":")
+				found = append(found, configSecret{Key: key, Value: value, Kind: "extra header", Token: token, Masked: key + " = " + name + ": ****"})
+			}
+		case strings.HasPrefix(lower, "url.") && strings.HasSuffix(lower, ".insteadof"):
+			base := key[len("url.") : len(key)-len(".insteadof")]
+			if token, masked := urlSecret(base); token != "" {
+				found = append(found, configSecret{Key: key, Value: value, Kind: "insteadOf", Token: token, Masked: "url." + masked + ".insteadOf = " + value})
+			}
+		}
+	}
+	return found
+}
+
+// repoConfigSecrets scans the current repository's local git config
+func repoConfigSecrets() ([]configSecret, error) {
+	output, err := exec.Command("git", "config", "--local", "-z", "--list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+	return scanConfigSecrets(string(output)), nil
+}
+
+func printConfigSecrets(secrets []configSecret) {
+	for _, secret := range secrets {
+		fmt.Printf("  %s %s %s\n", ui.ErrorText.Render("✗"), secret.Masked, ui.MutedText.Render("("+secret.Kind+")"))
+	}
+}
+
+func auditSecrets() error {
+	if !isGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+	secrets, err := repoConfigSecrets()
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		fmt.Println(ui.SuccessText.Render("✓ No credentials found in the repository config"))
+		return nil
+	}
+
+	fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  %d credential(s) stored in .git/config:", len(secrets))))
+	printConfigSecrets(secrets)
+	return offerSecretMove(secrets, auditSecretsYes)
+}
+
+// offerSecretMove asks to move found credentials into the keyring of the PAT
+// identity the repository is bound to
+func offerSecretMove(secrets []configSecret, yes bool) error {
+	marker, _ := getGitConfigLocal("gitx.bound")
+	identity, err := config.FindIdentityByAlias(marker)
+	if marker == "" || err != nil || identity.AuthMethod != "pat" {
+		fmt.Println(ui.MutedText.Render("  Bind this repository to a PAT identity ('gitx bind <alias>') to move them into the keyring."))
+		return nil
+	}
+
+	if !yes {
+		fmt.Printf("Move the token into the keyring for '%s' and remove it from .git/config? (y/n) [n]: ", identity.Alias)
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Left unchanged.")
+			return nil
+		}
+	}
+	return moveConfigSecrets(identity, secrets)
+}
+
+// moveConfigSecrets stores the first token found as the identity's PAT and
+// strips every found credential from the repository config
+func moveConfigSecrets(identity *config.Identity, secrets []configSecret) error {
+	token := secrets[0].Token
+	for _, secret := range secrets[1:] {
+		if secret.Token != token {
+			fmt.Println(ui.WarningText.Render("⚠️  Found more than one distinct token; storing the one from " + secrets[0].Kind))
+			break
+		}
+	}
+	if err := secretStore.Store(identity.Alias, "pat", token); err != nil {
+		return fmt.Errorf("failed to store PAT: %w", err)
+	}
+	fmt.Println(ui.SuccessText.Render("✓ PAT stored in keychain for '" + identity.Alias + "'"))
+
+	for _, secret := range secrets {
+		var err error
+		switch secret.Kind {
+		case "remote URL":
+			err = exec.Command("git", "config", "--local", "--replace-all", secret.Key, stripUserinfo(secret.Value), "^"+regexp.QuoteMeta(secret.Value)+"$").Run()
+		case "extra header":
+			err = exec.Command("git", "config", "--local", "--unset", secret.Key, "^"+regexp.QuoteMeta(secret.Value)+"$").Run()
+		case "insteadOf":
+			base := secret.Key[len("url.") : len(secret.Key)-len(".insteadof")]
+			err = exec.Command("git", "config", "--local", "--unset", secret.Key, "^"+regexp.QuoteMeta(secret.Value)+"$").Run()
+			// Keep the rewrite itself unless it would now map a URL onto itself
+			if clean := stripUserinfo(base); err == nil && clean != secret.Value {
+				err = exec.Command("git", "config", "--local", "--add", "url."+clean+".insteadOf", secret.Value).Run()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render(fmt.Sprintf("⚠️  Failed to clean %s: %v", secret.Masked, err)))
+			continue
+		}
+		fmt.Printf("  %s %s\n", ui.SuccessText.Render("✓ Cleaned"), secret.Masked)
+	}
+
+	return useGitxCredentialHelper("")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	auditCmd.AddCommand(auditCommitsCmd)
+}
+
+var auditCommitsCmd = &cobra.Command{
+	Use:   "commits [revision-range]",
+	Short: "Find commits authored with an email the bound identity doesn't use",
+	Long: `Check the author email of commits against the emails of the identity the repository
+is bound to: its primary email and any added with 'gitx edit identity --add-email'.
+Without a revision range, the commits not yet on any remote are checked, which is what
+the next push would send.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := auditCommits(args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// commitAuthor is a commit and the email it was authored with
+type commitAuthor struct {
+	Hash    string
+	Email   string
+	Subject string
+}
+
+// parseCommitAuthors reads git log output in the "%h%x00%ae%x00%s" format
+func parseCommitAuthors(log string) []commitAuthor {
+	var commits []commitAuthor
+	for _, line := range strings.Split(log, "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, commitAuthor{Hash: fields[0], Email: fields[1], Subject: fields[2]})
+	}
+	return commits
+}
+
+// foreignCommits returns the commits not authored with one of identity's emails
+func foreignCommits(identity *config.Identity, commits []commitAuthor) []commitAuthor {
+	var foreign []commitAuthor
+	for _, commit := range commits {
+		if !identity.AllowsEmail(commit.Email) {
+			foreign = append(foreign, commit)
+		}
+	}
+	return foreign
+}
+
+func auditCommits(args []string) error {
+	if !isGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+	marker, _ := getGitConfigLocal("gitx.bound")
+	if marker == "" {
+		return fmt.Errorf("repository is not bound to an identity")
+	}
+	identity, err := config.FindIdentityByAlias(marker)
+	if err != nil {
+		return err
+	}
+
+	revisions := []string{"HEAD", "--not", "--remotes"}
+	if len(args) == 1 {
+		revisions = []string{args[0]}
+	}
+	output, err := exec.Command("git", append([]string{"log", "--format=%h%x00%ae%x00%s"}, revisions...)...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to list commits: %w", err)
+	}
+	commits := parseCommitAuthors(string(output))
+	foreign := foreignCommits(identity, commits)
+	if len(foreign) == 0 {
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ %d %s checked, all authored as %s", len(commits), plural(len(commits), "commit", "commits"), strings.Join(identity.AllowedEmails(), " or "))))
+		return nil
+	}
+
+	for _, commit := range foreign {
+		fmt.Printf("  %s %s %s %s\n", ui.ErrorText.Render("✗"), commit.Hash, ui.WarningText.Render(commit.Email), commit.Subject)
+	}
+	fmt.Println(ui.MutedText.Render(fmt.Sprintf("  Allowed for '%s': %s", identity.Alias, strings.Join(identity.AllowedEmails(), ", "))))
+	return fmt.Errorf("%d of %d %s authored with another email", len(foreign), len(commits), plural(len(commits), "commit", "commits"))
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
 	"github.com/csawai/git-identity-switcher/internal/ssh"
 	"github.com/csawai/git-identity-switcher/internal/ui"
 	"github.com/spf13/cobra"
@@ -52,13 +55,46 @@ func bindIdentity(alias string) error {
 		fmt.Println("[DRY RUN] Would make the following changes:")
 		fmt.Printf("  user.name: '%s' -> '%s'\n", currentName, identity.Name)
 		fmt.Printf("  user.email: '%s' -> '%s'\n", currentEmail, identity.Email)
+		if len(identity.Emails) > 0 {
+			fmt.Printf("  gitx.email: -> %s\n", strings.Join(identity.AllowedEmails(), ", "))
+		}
 		if identity.SSHHostAlias != "" {
 			newRemote := strings.Replace(currentRemote, "git@github.com:", fmt.Sprintf("git@%s:", identity.SSHHostAlias), 1)
 			fmt.Printf("  remote URL: '%s' -> '%s'\n", currentRemote, newRemote)
 		}
+		printGitConfigOverrides(identity.GitConfig)
+		if identity.AuthMethod == "pat" {
+			fmt.Printf("  credential.helper: -> '%s'\n", gitxCredentialHelper())
+			if warning := patWarning(secretStore, alias); warning != "" {
+				fmt.Printf("  %s\n", ui.WarningText.Render("⚠️  "+warning))
+			}
+		}
 		return nil
 	}
 
+	if err := applyIdentity(identity); err != nil {
+		return err
+	}
+
+	if topLevel, err := repoTopLevel(); err == nil {
+		if err := config.RecordBinding(topLevel, alias, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to record binding: "+err.Error()))
+		}
+	}
+
+	fmt.Println(ui.Celebration(fmt.Sprintf("Repository bound to identity '%s'", alias)))
+
+	if secrets, err := repoConfigSecrets(); err == nil && len(secrets) > 0 {
+		fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  %d credential(s) stored in .git/config:", len(secrets))))
+		printConfigSecrets(secrets)
+		return offerSecretMove(secrets, false)
+	}
+	return nil
+}
+
+// applyIdentity points the current repository's user, remote and credential
+// helper at identity
+func applyIdentity(identity *config.Identity) error {
 	// Set user.name
 	if err := setGitConfig("user.name", identity.Name); err != nil {
 		return fmt.Errorf("failed to set user.name: %w", err)
@@ -71,15 +107,33 @@ func bindIdentity(alias string) error {
 
 	// Set a marker to track that gitx bound this identity
 	// This allows us to reliably detect binding for HTTPS repos
-	if err := setGitConfig("gitx.bound", alias); err != nil {
+	if err := setGitConfig("gitx.bound", identity.Alias); err != nil {
 		return fmt.Errorf("failed to set gitx.bound marker: %w", err)
 	}
 
+	// Record every email the identity may commit with, for the pre-push hook
+	if err := setGitxEmails(identity.AllowedEmails()); err != nil {
+		return fmt.Errorf("failed to set gitx.email: %w", err)
+	}
+
+	// Apply the identity's own git config, restoring what a previous identity set
+	if err := applyGitConfigOverrides(identity.GitConfig); err != nil {
+		return err
+	}
+
 	// Ensure SSH config entry exists for SSH identities
-	if identity.AuthMethod == "ssh" && identity.SSHHostAlias != "" && identity.SSHKeyPath != "" {
+	if identity.AuthMethod == "ssh" && identity.SSHHostAlias != "" && identity.SSHKeyPath != "" && !identity.ExternalHostEntry {
+		if err := ensureKnownHosts(); err != nil {
+			return fmt.Errorf("failed to seed known_hosts: %w", err)
+		}
 		if err := ssh.AddSSHConfigEntry(identity.SSHHostAlias, identity.SSHKeyPath); err != nil {
 			return fmt.Errorf("failed to update SSH config: %w", err)
 		}
+		if identity.IsolatedAgent {
+			if err := ensureIdentityAgent(identity); err != nil {
+				return fmt.Errorf("failed to start isolated agent: %w", err)
+			}
+		}
 	}
 
 	// Update remote URL based on auth method
@@ -88,26 +142,52 @@ func bindIdentity(alias string) error {
 			return fmt.Errorf("failed to update remote URL: %w", err)
 		}
 	} else if identity.AuthMethod == "pat" {
+		if warning := patWarning(secretStore, identity.Alias); warning != "" {
+			fmt.Println(ui.WarningText.Render("⚠️  " + warning))
+		}
 		// For PAT, use HTTPS with credential helper
 		if err := updateRemoteURLToHTTPS(); err != nil {
 			return fmt.Errorf("failed to update remote URL: %w", err)
 		}
-		// Configure credential helper
-		if err := setGitConfig("credential.helper", "osxkeychain"); err != nil {
-			// Try alternative for Linux
-			setGitConfig("credential.helper", "store")
+		// Serve the PAT from the gitx keyring rather than a plaintext store
+		if err := useGitxCredentialHelper(""); err != nil {
+			return err
 		}
 	}
-
-	fmt.Println(ui.Celebration(fmt.Sprintf("Repository bound to identity '%s'", alias)))
+	if identity.AuthMethod != "pat" {
+		// A PAT identity bound before may have left gitx's credential helper behind
+		removeGitxCredentialHelper()
+	}
 	return nil
 }
 
+// patWarning returns a message when the identity's PAT can't be found in store
+func patWarning(store keychain.SecretStore, alias string) string {
+	if _, err := store.Get(alias, "pat"); err != nil {
+		if errors.Is(err, keychain.ErrSecretNotFound) {
+			return fmt.Sprintf("no PAT stored for '%s'; git will prompt for credentials", alias)
+		}
+		return "could not read PAT from keyring: " + err.Error()
+	}
+	return ""
+}
+
 func setGitConfig(key, value string) error {
 	cmd := exec.Command("git", "config", "--local", key, value)
 	return cmd.Run()
 }
 
+// setGitxEmails replaces the repository's gitx.email entries with emails
+func setGitxEmails(emails []string) error {
+	exec.Command("git", "config", "--local", "--unset-all", "gitx.email").Run()
+	for _, email := range emails {
+		if err := exec.Command("git", "config", "--local", "--add", "gitx.email", email).Run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func updateRemoteURL(hostAlias string) error {
 	// Get current remote URL
 	cmd := exec.Command("git", "remote", "get-url", "origin")
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// certExpiryWarning is how far ahead status and keys warn about expiring certificates
+const certExpiryWarning = 7 * 24 * time.Hour
+
+var (
+	certCAKey      string
+	certPrincipals []string
+	certValidity   string
+	certKeyID      string
+)
+
+func init() {
+	certSignCmd.Flags().StringVar(&certCAKey, "ca", "", "CA private key used to sign (required)")
+	certSignCmd.Flags().StringSliceVar(&certPrincipals, "principals", nil, "Certificate principals (default: the identity's GitHub username)")
+	certSignCmd.Flags().StringVar(&certValidity, "validity", "+52w", "Validity interval in ssh-keygen -V syntax")
+	certSignCmd.Flags().StringVar(&certKeyID, "id", "", "Certificate key ID (default: gitx-<alias>)")
+	certSignCmd.MarkFlagRequired("ca")
+	certCmd.AddCommand(certSignCmd)
+	certCmd.AddCommand(certShowCmd)
+	rootCmd.AddCommand(certCmd)
+}
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage SSH user certificates for identities",
+}
+
+var certSignCmd = &cobra.Command{
+	Use:   "sign [alias]",
+	Short: "Sign an identity's SSH key with a CA",
+	Long: `Sign the identity's public key with a CA key (ssh-keygen -s), store the certificate
+as <key>-cert.pub and add it as CertificateFile to the managed SSH host entry.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := signCertificate(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var certShowCmd = &cobra.Command{
+	Use:   "show [alias]",
+	Short: "Show an identity's SSH certificate",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := showCertificate(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func signCertificate(alias string) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	if identity.AuthMethod != "ssh" || identity.SSHKeyPath == "" {
+		return fmt.Errorf("identity '%s' does not use an SSH key", alias)
+	}
+	if identity.ExternalHostEntry {
+		return fmt.Errorf("SSH host %s is not managed by gitx; re-import it with 'gitx import ssh --move' first", identity.SSHHostAlias)
+	}
+
+	principals := certPrincipals
+	if len(principals) == 0 {
+		principals = []string{identity.GitHubUser}
+	}
+	keyID := certKeyID
+	if keyID == "" {
+		keyID = "gitx-" + alias
+	}
+
+	certPath, err := ssh.SignCertificate(certCAKey, identity.SSHKeyPath, keyID, principals, certValidity)
+	if err != nil {
+		return err
+	}
+
+	if identity.SSHHostAlias != "" {
+		if err := ssh.SetCertificateFile(identity.SSHHostAlias, certPath); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+	}
+
+	identity.SSHCertPath = certPath
+	if err := config.UpdateIdentity(*identity); err != nil {
+		return err
+	}
+
+	fmt.Println(ui.SuccessText.Render("✓ Certificate written: " + certPath))
+	return showCertificate(alias)
+}
+
+func showCertificate(alias string) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	if identity.SSHCertPath == "" {
+		return fmt.Errorf("identity '%s' has no SSH certificate (run 'gitx cert sign %s --ca <key>')", alias, alias)
+	}
+
+	cert, err := ssh.InspectCertificate(identity.SSHCertPath)
+	if err != nil {
+		return err
+	}
+
+	validity := "forever"
+	if !cert.ValidBefore.IsZero() {
+		validity = "until " + cert.ValidBefore.Format("2006-01-02 15:04")
+	}
+	content := fmt.Sprintf("📜 SSH Certificate for '%s'\n\n", alias)
+	content += fmt.Sprintf("Path:       %s\n", cert.Path)
+	content += fmt.Sprintf("Key ID:     %s\n", cert.KeyID)
+	content += fmt.Sprintf("Serial:     %s\n", cert.Serial)
+	content += fmt.Sprintf("CA:         %s\n", cert.SigningCA)
+	content += fmt.Sprintf("Principals: %s\n", strings.Join(cert.Principals, ", "))
+	content += fmt.Sprintf("Valid:      %s", validity)
+
+	if warning := certificateWarning(identity); warning != "" {
+		content += "\n\n" + ui.WarningText.Render("⚠️  "+warning)
+		fmt.Println(ui.WarningBox.Render(content))
+		return nil
+	}
+	fmt.Println(ui.InfoBox.Render(content))
+	return nil
+}
+
+// certificateWarning returns a message when the identity's certificate is missing,
+// expired or close to expiry, and "" otherwise
+func certificateWarning(identity *config.Identity) string {
+	if identity.SSHCertPath == "" {
+		return ""
+	}
+	cert, err := ssh.InspectCertificate(identity.SSHCertPath)
+	if err != nil {
+		return "certificate unreadable: " + err.Error()
+	}
+	now := time.Now()
+	if cert.Expired(now) {
+		return fmt.Sprintf("certificate expired on %s", cert.ValidBefore.Format("2006-01-02"))
+	}
+	if cert.ExpiresWithin(now, certExpiryWarning) {
+		return fmt.Sprintf("certificate expires on %s", cert.ValidBefore.Format("2006-01-02 15:04"))
+	}
+	return ""
+}
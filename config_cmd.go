@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var convertTo string
+
+func init() {
+	configConvertCmd.Flags().StringVar(&convertTo, "to", "", "Target format: json, yaml or toml")
+	configConvertCmd.MarkFlagRequired("to")
+	configCmd.AddCommand(configConvertCmd)
+	configCmd.AddCommand(configLintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the gitx config file",
+	Long: `gitx keeps identities in identities.json, identities.yaml or identities.toml in its
+config directory, whichever exists, and writes changes back in the same format.
+Comments in a YAML file are kept when gitx rewrites it.`,
+}
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Rewrite the identities file in another format",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := convertConfig(convertTo); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check every identity in the identities file",
+	Long: `Check each identity's alias, email, GitHub username, auth method, SSH host alias
+and key file, and that aliases and host aliases are unique. Exits non-zero when a
+problem is found.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lintConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func lintConfig() error {
+	path, err := config.GetIdentitiesPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	problems := cfg.Lint()
+	if len(problems) == 0 {
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ %s: %d %s, no problems found", path, len(cfg.Identities), plural(len(cfg.Identities), "identity", "identities"))))
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Println(ui.ErrorText.Render("  ✗ " + problem.Error()))
+	}
+	return fmt.Errorf("%d %s found in %s", len(problems), plural(len(problems), "problem", "problems"), path)
+}
+
+func convertConfig(name string) error {
+	format, err := config.ParseFormat(name)
+	if err != nil {
+		return err
+	}
+	from, to, err := config.ConvertConfig(format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(ui.SuccessText.Render("✓ Identities now in " + to))
+	if _, err := os.Stat(from + ".bak"); err == nil {
+		fmt.Println(ui.MutedText.Render("  previous file kept as " + from + ".bak"))
+	}
+	return nil
+}
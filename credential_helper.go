@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(credentialHelperCmd)
+}
+
+var credentialHelperCmd = &cobra.Command{
+	Use:    "credential-helper [get|store|erase]",
+	Short:  "Git credential helper that serves PATs from the gitx keyring",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Git only reads stdout for credentials; problems go to stderr and git
+		// falls through to the next helper or a prompt
+		if err := runCredentialHelper(args[0], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "gitx: %v\n", err)
+		}
+	},
+}
+
+// runCredentialHelper implements the git credential helper protocol. Only "get"
+// does anything: tokens are managed with gitx, not stored by git.
+func runCredentialHelper(action string, in io.Reader, out io.Writer) error {
+	if action != "get" {
+		return nil
+	}
+	// stdin belongs to git, so the file backend can't prompt for its passphrase
+	keychain.Interactive = false
+
+	attrs := map[string]string{}
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			attrs[key] = value
+		}
+	}
+	if attrs["protocol"] != "https" {
+		return nil
+	}
+
+	identity, err := credentialIdentity(attrs["username"])
+	if err != nil || identity == nil {
+		return err
+	}
+	hostAllowed := false
+	for _, host := range credentialHosts {
+		if strings.EqualFold(host, attrs["host"]) {
+			hostAllowed = true
+		}
+	}
+	if !hostAllowed {
+		return nil
+	}
+
+	token, err := secretStore.Get(identity.Alias, "pat")
+	if err != nil {
+		if errors.Is(err, keychain.ErrSecretNotFound) {
+			return fmt.Errorf("no PAT stored for '%s'", identity.Alias)
+		}
+		return err
+	}
+	fmt.Fprintf(out, "username=%s\npassword=%s\n", identity.GitHubUser, token)
+	return nil
+}
+
+// credentialIdentity picks the PAT identity for a credential request: the one
+// the current repository is bound to, else the one whose GitHub user was asked for
+func credentialIdentity(username string) (*config.Identity, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	marker, _ := getGitConfigLocal("gitx.bound")
+	for i := range cfg.Identities {
+		identity := &cfg.Identities[i]
+		if identity.AuthMethod != "pat" {
+			continue
+		}
+		if marker != "" && identity.Alias == marker {
+			return identity, nil
+		}
+		if marker == "" && username != "" && strings.EqualFold(identity.GitHubUser, username) {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+// gitxCredentialHelper returns the credential.helper value that runs this binary
+func gitxCredentialHelper() string {
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "gitx"
+	}
+	helper := "!" + shellQuote(exe)
+	// git runs the helper without our flags or, from scripts, environment, so
+	// an explicitly relocated config directory has to travel with it
+	if configDirFlag != "" || os.Getenv(config.ConfigDirEnv) != "" {
+		if dir, err := config.GetConfigDir(); err == nil {
+			helper += " --config=" + shellQuote(dir)
+		}
+	}
+	// the same goes for a backend chosen outside the config file
+	if keyringBackendFlag != "" || os.Getenv("GITX_KEYRING_BACKEND") != "" {
+		helper += " --keyring-backend=" + shellQuote(keychain.Backend)
+	}
+	return helper + " credential-helper"
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// isGitxCredentialHelper reports whether a credential.helper value is gitx's own
+func isGitxCredentialHelper(helper string) bool {
+	return strings.HasPrefix(helper, "!") && strings.HasSuffix(helper, " credential-helper")
+}
+
+// useGitxCredentialHelper makes the repository at dir ("" for the current one)
+// ask only gitx for credentials. The empty entry resets helpers inherited from
+// the global config, such as store.
+func useGitxCredentialHelper(dir string) error {
+	git := func(args ...string) *exec.Cmd {
+		if dir != "" {
+			args = append([]string{"-C", dir}, args...)
+		}
+		return exec.Command("git", args...)
+	}
+	git("config", "--local", "--unset-all", "credential.helper").Run() // may not be set
+	if err := git("config", "--local", "--add", "credential.helper", "").Run(); err != nil {
+		return fmt.Errorf("failed to reset credential.helper: %w", err)
+	}
+	if err := git("config", "--local", "--add", "credential.helper", gitxCredentialHelper()).Run(); err != nil {
+		return fmt.Errorf("failed to set credential.helper: %w", err)
+	}
+	return nil
+}
+
+// repoTopLevel returns the work tree root of the current repository
+func repoTopLevel() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+)
+
+// usePATIdentities points gitx at a temporary config holding identities and
+// an in-memory secret store with a PAT for each, named "<alias>-token"
+func usePATIdentities(t *testing.T, identities ...config.Identity) *keychain.MemoryStore {
+	t.Helper()
+	t.Setenv(config.ConfigDirEnv, t.TempDir())
+	oldStore := secretStore
+	t.Cleanup(func() { secretStore = oldStore })
+	store := keychain.NewMemoryStore()
+	secretStore = store
+	for _, identity := range identities {
+		if err := config.AddIdentity(identity); err != nil {
+			t.Fatal(err)
+		}
+		if identity.AuthMethod == "pat" {
+			store.Store(identity.Alias, "pat", identity.Alias+"-token")
+		}
+	}
+	return store
+}
+
+func TestRunCredentialHelper(t *testing.T) {
+	defer func() { keychain.Interactive = true }()
+	store := usePATIdentities(t,
+		config.Identity{Alias: "work", Name: "Work", Email: "work@example.com", GitHubUser: "octo", AuthMethod: "pat"},
+		config.Identity{Alias: "home", Name: "Home", Email: "home@example.com", GitHubUser: "cat", AuthMethod: "pat"},
+		config.Identity{Alias: "keys", Name: "Keys", Email: "keys@example.com", GitHubUser: "dog", AuthMethod: "ssh"},
+	)
+	store.Delete("home", "pat")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(t.TempDir())
+
+	helper := func(action, request string) (string, error) {
+		var out bytes.Buffer
+		err := runCredentialHelper(action, strings.NewReader(request), &out)
+		return out.String(), err
+	}
+
+	// Outside a bound repository the identity is picked by username
+	out, err := helper("get", "protocol=https\nhost=github.com\nusername=octo\n\n")
+	if err != nil || out != "username=octo\npassword=work-token\n" {
+		t.Errorf("get = %q, %v; want work's PAT", out, err)
+	}
+	for _, request := range []string{
+		"protocol=https\nhost=github.com\n\n",               // no username, no binding
+		"protocol=https\nhost=github.com\nusername=dog\n\n", // ssh identity
+		"protocol=https\nhost=gitlab.com\nusername=octo\n\n",
+		"protocol=http\nhost=github.com\nusername=octo\n\n",
+	} {
+		if out, err := helper("get", request); out != "" || err != nil {
+			t.Errorf("get %q = %q, %v; want nothing", request, out, err)
+		}
+	}
+	if _, err := helper("get", "protocol=https\nhost=github.com\nusername=cat\n\n"); err == nil {
+		t.Error("expected an error for an identity without a stored PAT")
+	}
+
+	// Tokens are managed with gitx, so store and erase leave the keyring alone
+	for _, action := range []string{"store", "erase"} {
+		out, err := helper(action, "protocol=https\nhost=github.com\nusername=octo\npassword=typed\n\n")
+		if out != "" || err != nil {
+			t.Errorf("%s = %q, %v; want nothing", action, out, err)
+		}
+	}
+	if token, _ := store.Get("work", "pat"); token != "work-token" {
+		t.Errorf("PAT after store/erase = %q", token)
+	}
+
+	// In a bound repository the bound identity answers, whatever the username
+	exec.Command("git", "init").Run()
+	exec.Command("git", "config", "gitx.bound", "work").Run()
+	out, _ = helper("get", "protocol=https\nhost=github.com\nusername=cat\n\n")
+	if out != "username=octo\npassword=work-token\n" {
+		t.Errorf("get in bound repository = %q, want work's PAT", out)
+	}
+}
+
+func TestGitxCredentialHelperKeyringBackend(t *testing.T) {
+	defer func() { keyringBackendFlag, keychain.Backend = "", "" }()
+	t.Setenv("GITX_KEYRING_BACKEND", "")
+
+	// A backend from the config file is found again through --config
+	keychain.Backend = "pass"
+	if helper := gitxCredentialHelper(); strings.Contains(helper, "--keyring-backend") {
+		t.Errorf("helper %q passes a backend the config file already sets", helper)
+	}
+
+	keyringBackendFlag, keychain.Backend = "file", "file"
+	helper := gitxCredentialHelper()
+	if !strings.Contains(helper, " --keyring-backend='file' ") || !isGitxCredentialHelper(helper) {
+		t.Errorf("helper %q doesn't pass --keyring-backend", helper)
+	}
+
+	keyringBackendFlag = ""
+	t.Setenv("GITX_KEYRING_BACKEND", "keyctl")
+	keychain.Backend = "keyctl"
+	if helper := gitxCredentialHelper(); !strings.Contains(helper, " --keyring-backend='keyctl' ") {
+		t.Errorf("helper %q doesn't pass the backend from the environment", helper)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	editName       string
+	editEmail      string
+	editGitHubUser string
+	editAuth       string
+	editKeyPath    string
+	editAddEmail   []string
+	editRmEmail    []string
+	editGitConfig  []string
+	editUnsetGit   []string
+	editApply      bool
+	editDryRun     bool
+)
+
+func init() {
+	editIdentityCmd.Flags().StringVar(&editName, "name", "", "New git user.name")
+	editIdentityCmd.Flags().StringVar(&editEmail, "email", "", "New git user.email")
+	editIdentityCmd.Flags().StringArrayVar(&editAddEmail, "add-email", nil, "Also allow commits with this email (repeatable)")
+	editIdentityCmd.Flags().StringArrayVar(&editRmEmail, "remove-email", nil, "Stop allowing this additional email (repeatable)")
+	editIdentityCmd.Flags().StringVar(&editGitHubUser, "github-user", "", "New GitHub username")
+	editIdentityCmd.Flags().StringArrayVar(&editGitConfig, "git-config", nil, "Git config key=value to set in bound repositories (repeatable)")
+	editIdentityCmd.Flags().StringArrayVar(&editUnsetGit, "unset-git-config", nil, "Stop setting this git config key (repeatable)")
+	editIdentityCmd.Flags().StringVar(&editAuth, "auth", "", "Switch the auth method (ssh or pat)")
+	editIdentityCmd.Flags().StringVar(&editKeyPath, "key", "", "Existing SSH private key to use instead of the current one, or when switching to ssh")
+	editIdentityCmd.Flags().BoolVar(&editApply, "apply", false, "Re-apply the identity to every repository bound to it")
+	editIdentityCmd.Flags().BoolVar(&editDryRun, "dry-run", false, "Show what would be changed without making changes")
+	rootCmd.AddCommand(editIdentityCmd)
+}
+
+var editIdentityCmd = &cobra.Command{
+	Use:   "edit identity [alias]",
+	Short: "Change an identity's name, email, GitHub user or auth method",
+	Long: `Update an identity in place, keeping its keys and secrets. Without flags, gitx
+prompts for each field with the current value as the default. Switching to pat
+stores a token; switching to ssh generates a key unless the identity already has
+one or --key is given. --key on its own points an ssh identity at another key. With --apply (or when confirmed interactively) the new
+values are written to every repository bound to the identity.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := editIdentity(cmd, identityArg(args)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// identityArg returns the alias from "<alias>" or "identity <alias>"
+func identityArg(args []string) string {
+	return identityArgs(args)[0]
+}
+
+// identityArgs drops the literal "identity" of "<verb> identity <alias>..."
+// invocations, which cobra passes on as an argument
+func identityArgs(args []string) []string {
+	if len(args) > 1 && args[0] == "identity" {
+		return args[1:]
+	}
+	return args
+}
+
+func editIdentity(cmd *cobra.Command, alias string) error {
+	current, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	identity := *current
+	reader := bufio.NewReader(os.Stdin)
+
+	flags := cmd.Flags()
+	interactive := true
+	for _, flag := range []string{"name", "email", "add-email", "remove-email", "github-user", "auth", "key", "git-config", "unset-git-config"} {
+		if flags.Changed(flag) {
+			interactive = false
+		}
+	}
+	if interactive {
+		fmt.Println(ui.HeaderStyle.Render("✏️  Edit Identity: " + alias))
+		fmt.Println(ui.SectionDivider())
+		fmt.Println()
+		identity.Name = promptDefault(reader, "👤 Name", identity.Name)
+		identity.Email = promptDefault(reader, "📧 Email", identity.Email)
+		others := promptDefault(reader, "📧 Other allowed emails (comma-separated, '-' for none)", strings.Join(identity.Emails, ","))
+		identity.Emails = nil
+		for _, email := range strings.Split(others, ",") {
+			if email = strings.TrimSpace(email); email != "" && email != "-" {
+				identity.Emails = append(identity.Emails, email)
+			}
+		}
+		identity.GitHubUser = promptDefault(reader, "🐙 GitHub username", identity.GitHubUser)
+		identity.AuthMethod = strings.ToLower(promptDefault(reader, "🔐 Auth method (ssh/pat)", identity.AuthMethod))
+	} else {
+		if flags.Changed("name") {
+			identity.Name = strings.TrimSpace(editName)
+		}
+		if flags.Changed("email") {
+			identity.Email = strings.TrimSpace(editEmail)
+		}
+		if flags.Changed("github-user") {
+			identity.GitHubUser = strings.TrimSpace(editGitHubUser)
+		}
+		identity.Emails = editEmails(current.Emails, editAddEmail, editRmEmail)
+		if identity.GitConfig, err = withGitConfig(current.GitConfig, editGitConfig, editUnsetGit); err != nil {
+			return err
+		}
+		if flags.Changed("auth") {
+			identity.AuthMethod = strings.ToLower(strings.TrimSpace(editAuth))
+		}
+		if flags.Changed("key") && identity.AuthMethod != "ssh" {
+			return fmt.Errorf("--key only applies to ssh identities")
+		}
+	}
+
+	// The primary email isn't also listed as an additional one
+	identity.Emails = editEmails(identity.Emails, nil, []string{identity.Email})
+	if len(identity.Emails) == 0 {
+		identity.Emails = nil
+	}
+
+	// A missing key file doesn't block replacing the key or moving to a PAT
+	keyUnused := flags.Changed("key") || identity.AuthMethod == "pat"
+	var problems []error
+	for _, problem := range identity.Problems() {
+		if !keyUnused || !errors.Is(problem, config.ErrKeyNotFound) {
+			problems = append(problems, problem)
+		}
+	}
+	if err := errors.Join(problems...); err != nil {
+		return err
+	}
+	switching := identity.AuthMethod != current.AuthMethod
+	rekeying := !switching && flags.Changed("key")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	repos := cfg.ReposBoundTo(alias)
+
+	if editDryRun {
+		fmt.Println("[DRY RUN] Would make the following changes:")
+		printChange("name", current.Name, identity.Name)
+		printChange("email", current.Email, identity.Email)
+		printChange("other emails", strings.Join(current.Emails, ", "), strings.Join(identity.Emails, ", "))
+		printChange("GitHub user", current.GitHubUser, identity.GitHubUser)
+		keys := map[string]string{}
+		for key := range current.GitConfig {
+			keys[key] = ""
+		}
+		for key := range identity.GitConfig {
+			keys[key] = ""
+		}
+		for _, key := range sortedKeys(keys) {
+			printChange("git config "+key, current.GitConfig[key], identity.GitConfig[key])
+		}
+		printChange("auth", current.AuthMethod, identity.AuthMethod)
+		if switching && identity.AuthMethod == "pat" {
+			fmt.Println("  Store a PAT in the keychain (SSH key kept)")
+		}
+		if (switching || rekeying) && identity.AuthMethod == "ssh" {
+			fmt.Printf("  SSH key: %s\n", editKeyDescription(current))
+		}
+		for _, repo := range repos {
+			fmt.Printf("  Bound repository: %s\n", repo.Path)
+		}
+		return nil
+	}
+
+	if switching || rekeying {
+		if err := switchAuthMethod(reader, &identity); err != nil {
+			return err
+		}
+	}
+
+	if err := config.UpdateIdentity(identity); err != nil {
+		return err
+	}
+	fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Identity '%s' updated", alias)))
+
+	if len(repos) == 0 {
+		return nil
+	}
+	apply := editApply
+	if !apply && interactive {
+		fmt.Printf("Re-apply to %d bound %s? (y/n) [y]: ", len(repos), plural(len(repos), "repository", "repositories"))
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		apply = response == "" || response == "y" || response == "yes"
+	}
+	if !apply {
+		fmt.Println(ui.MutedText.Render(fmt.Sprintf("  %d bound %s still use the old values; run 'gitx bind %s' in each or re-run with --apply", len(repos), plural(len(repos), "repository", "repositories"), alias)))
+		return nil
+	}
+	return reapplyIdentity(&identity, repos)
+}
+
+// editEmails returns emails with add appended and remove taken out, ignoring case
+func editEmails(emails, add, remove []string) []string {
+	var result []string
+	keep := func(email string) bool {
+		for _, other := range remove {
+			if strings.EqualFold(strings.TrimSpace(other), email) {
+				return false
+			}
+		}
+		for _, other := range result {
+			if strings.EqualFold(other, email) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, email := range append(append([]string{}, emails...), add...) {
+		if email = strings.TrimSpace(email); email != "" && keep(email) {
+			result = append(result, email)
+		}
+	}
+	return result
+}
+
+// promptDefault asks for a value, returning def when the answer is empty
+func promptDefault(reader *bufio.Reader, label, def string) string {
+	fmt.Printf("%s [%s]: ", ui.InfoText.Render(label), def)
+	value, _ := reader.ReadString('\n')
+	if value = strings.TrimSpace(value); value == "" {
+		return def
+	}
+	return value
+}
+
+func printChange(field, from, to string) {
+	if from != to {
+		fmt.Printf("  %s: '%s' -> '%s'\n", field, from, to)
+	}
+}
+
+// editKeyDescription says which key switching identity to ssh would use
+func editKeyDescription(identity *config.Identity) string {
+	switch {
+	case editKeyPath != "":
+		return editKeyPath + " (existing)"
+	case identity.SSHKeyPath != "":
+		return identity.SSHKeyPath + " (kept from before)"
+	}
+	return "generate a new key"
+}
+
+// switchAuthMethod sets up what identity's new auth method needs, or the key
+// given with --key. Keys and tokens of the previous method are kept, so
+// switching back is cheap.
+func switchAuthMethod(reader *bufio.Reader, identity *config.Identity) error {
+	if identity.AuthMethod == "pat" {
+		if err := readAndStorePAT(reader, secretStore, identity.Alias); err != nil {
+			return err
+		}
+		fmt.Println("✓ PAT stored securely in keychain")
+		info, err := promptPATInfo(reader, nil)
+		if err != nil {
+			return err
+		}
+		identity.PAT = info
+		return nil
+	}
+
+	// The token's metadata no longer applies; the token itself stays in the keyring
+	identity.PAT = nil
+	if err := ensureKnownHosts(); err != nil {
+		return fmt.Errorf("failed to seed known_hosts: %w", err)
+	}
+
+	keyPath := identity.SSHKeyPath
+	if editKeyPath != "" {
+		expanded, err := ssh.ExpandPath(editKeyPath)
+		if err != nil {
+			return err
+		}
+		if keyPath, err = filepath.Abs(expanded); err != nil {
+			return err
+		}
+		if _, err := os.Stat(keyPath + ".pub"); err != nil {
+			return fmt.Errorf("SSH public key not found: %s.pub", keyPath)
+		}
+	}
+	generated := false
+	if keyPath == "" {
+		if err := ui.SpinnerWithFunc("Generating SSH key", func() error {
+			var err error
+			keyPath, err = ssh.GenerateSSHKey(identity.Alias)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to generate SSH key: %w", err)
+		}
+		generated = true
+	}
+	if _, err := os.Stat(keyPath); err != nil {
+		return fmt.Errorf("SSH key not found: %s", keyPath)
+	}
+
+	identity.SSHKeyPath = keyPath
+	if identity.SSHHostAlias == "" {
+		identity.SSHHostAlias = fmt.Sprintf("github.com-%s", identity.Alias)
+	}
+	if !identity.ExternalHostEntry {
+		if err := ssh.AddSSHConfigEntry(identity.SSHHostAlias, keyPath); err != nil {
+			return fmt.Errorf("failed to add SSH config: %w", err)
+		}
+	} else {
+		fmt.Println(ui.MutedText.Render(fmt.Sprintf("  Host %s is in your own SSH config; point its IdentityFile at %s", identity.SSHHostAlias, keyPath)))
+	}
+	fmt.Println(ui.SuccessText.Render("✓ Using SSH key: " + keyPath))
+	if generated {
+		fmt.Printf("  Add %s.pub to GitHub: https://github.com/settings/ssh/new\n", keyPath)
+	}
+	return nil
+}
+
+// reapplyIdentity binds every repository in repos to identity again. Repos that
+// are gone or have since been bound elsewhere are skipped.
+func reapplyIdentity(identity *config.Identity, repos []config.BoundRepo) error {
+	failed := 0
+	for _, repo := range repos {
+		marker, err := exec.Command("git", "-C", repo.Path, "config", "--local", "--get", "gitx.bound").Output()
+		if err != nil || strings.TrimSpace(string(marker)) != identity.Alias {
+			fmt.Println(ui.MutedText.Render("  - " + repo.Path + " (no longer bound, skipped)"))
+			continue
+		}
+		if err := inDir(repo.Path, func() error { return applyIdentity(identity) }); err != nil {
+			fmt.Println(ui.ErrorText.Render(fmt.Sprintf("  ✗ %s: %v", repo.Path, err)))
+			failed++
+			continue
+		}
+		fmt.Println(ui.SuccessText.Render("  ✓ " + repo.Path))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to update %d %s", failed, plural(failed, "repository", "repositories"))
+	}
+	return nil
+}
+
+// inDir runs fn with dir as the working directory
+func inDir(dir string, fn func() error) error {
+	previous, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer os.Chdir(previous)
+	return fn()
+}
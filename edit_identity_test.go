@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// parseFlags resets cmd's flags to their defaults and parses args into them,
+// as a new invocation would. They are reset again when the test ends.
+func parseFlags(t *testing.T, cmd *cobra.Command, args ...string) {
+	t.Helper()
+	resetFlags(cmd)
+	t.Cleanup(func() { resetFlags(cmd) })
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resetFlags puts every flag of cmd back to its default, unset
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+// withStdin makes os.Stdin read input until the test ends
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "stdin")
+	os.WriteFile(file, []byte(input), 0600)
+	stdin, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin := os.Stdin
+	t.Cleanup(func() { os.Stdin = oldStdin; stdin.Close() })
+	os.Stdin = stdin
+}
+
+// writeTestKey creates a fake key pair and returns the private key's path
+func writeTestKey(t *testing.T, path string) string {
+	t.Helper()
+	os.WriteFile(path, []byte("private"), 0600)
+	os.WriteFile(path+".pub", []byte("ssh-ed25519 AAAA test"), 0644)
+	return path
+}
+
+// useEditTestEnv sets up a temporary config, SSH directory and secret store
+// holding an ssh identity "work" and a pat identity "home"
+func useEditTestEnv(t *testing.T) (*keychain.MemoryStore, string) {
+	t.Helper()
+	sshDir := t.TempDir()
+	oldConfig, oldKeyDir := ssh.ConfigFile, ssh.KeyDir
+	t.Cleanup(func() { ssh.ConfigFile, ssh.KeyDir = oldConfig, oldKeyDir })
+	ssh.ConfigFile = filepath.Join(sshDir, "config")
+	ssh.KeyDir = sshDir
+
+	key := writeTestKey(t, filepath.Join(sshDir, "gitx_work"))
+	if err := ssh.AddSSHConfigEntry("github.com-work", key); err != nil {
+		t.Fatal(err)
+	}
+	store := usePATIdentities(t,
+		config.Identity{Alias: "work", Name: "Work", Email: "work@example.com", GitHubUser: "octo", AuthMethod: "ssh", SSHKeyPath: key, SSHHostAlias: "github.com-work"},
+		config.Identity{Alias: "home", Name: "Home", Email: "home@example.com", GitHubUser: "cat", AuthMethod: "pat"},
+	)
+	withStdin(t, "")
+	return store, sshDir
+}
+
+func TestEditIdentityFlags(t *testing.T) {
+	useEditTestEnv(t)
+	parseFlags(t, editIdentityCmd, "--name", "Work Person", "--add-email", "w@example.org", "--git-config", "pull.rebase=true")
+
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := config.FindIdentityByAlias("work")
+	if identity.Name != "Work Person" || identity.Email != "work@example.com" || identity.GitHubUser != "octo" {
+		t.Errorf("fields after edit = %+v", identity)
+	}
+	if strings.Join(identity.Emails, ",") != "w@example.org" || identity.GitConfig["pull.rebase"] != "true" {
+		t.Errorf("emails %v, git config %v", identity.Emails, identity.GitConfig)
+	}
+}
+
+func TestEditIdentityKey(t *testing.T) {
+	_, sshDir := useEditTestEnv(t)
+	newKey := writeTestKey(t, filepath.Join(sshDir, "id_work"))
+	parseFlags(t, editIdentityCmd, "--key", newKey)
+
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := config.FindIdentityByAlias("work")
+	if identity.SSHKeyPath != newKey || identity.Name != "Work" {
+		t.Errorf("identity after --key = %+v", identity)
+	}
+	data, _ := os.ReadFile(ssh.ConfigFile)
+	if !strings.Contains(string(data), "IdentityFile "+newKey) {
+		t.Errorf("SSH config doesn't use the new key:\n%s", data)
+	}
+
+	if err := editIdentity(editIdentityCmd, "home"); err == nil {
+		t.Error("expected --key on a pat identity to fail")
+	}
+}
+
+func TestEditIdentitySwitchAuth(t *testing.T) {
+	store, _ := useEditTestEnv(t)
+	key := filepath.Join(ssh.KeyDir, "gitx_work")
+
+	// ssh -> pat stores the token and keeps the key for switching back
+	withStdin(t, "work-pat\n2099-01-02\nrepo\n\n")
+	parseFlags(t, editIdentityCmd, "--auth", "pat")
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := config.FindIdentityByAlias("work")
+	if identity.AuthMethod != "pat" || identity.SSHKeyPath != key {
+		t.Errorf("identity after switching to pat = %+v", identity)
+	}
+	if identity.PAT == nil || identity.PAT.ExpiresAt == nil || strings.Join(identity.PAT.Scopes, ",") != "repo" {
+		t.Errorf("PAT details = %+v", identity.PAT)
+	}
+	if token, _ := store.Get("work", "pat"); token != "work-pat" {
+		t.Errorf("stored PAT = %q", token)
+	}
+
+	// pat -> ssh reuses the kept key and drops the token's details
+	parseFlags(t, editIdentityCmd, "--auth", "ssh")
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	identity, _ = config.FindIdentityByAlias("work")
+	if identity.AuthMethod != "ssh" || identity.SSHKeyPath != key || identity.PAT != nil {
+		t.Errorf("identity after switching back to ssh = %+v", identity)
+	}
+	if token, _ := store.Get("work", "pat"); token != "work-pat" {
+		t.Errorf("PAT was removed when switching to ssh: %q", token)
+	}
+}
+
+func TestEditIdentityMissingKey(t *testing.T) {
+	_, sshDir := useEditTestEnv(t)
+	os.Remove(filepath.Join(sshDir, "gitx_work"))
+
+	parseFlags(t, editIdentityCmd, "--name", "Work Person")
+	if err := editIdentity(editIdentityCmd, "work"); err == nil {
+		t.Error("expected an edit that keeps the missing key to fail")
+	}
+
+	// Pointing the identity at another key fixes it
+	newKey := writeTestKey(t, filepath.Join(sshDir, "id_work"))
+	parseFlags(t, editIdentityCmd, "--key", newKey)
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	identity, _ := config.FindIdentityByAlias("work")
+	if identity.SSHKeyPath != newKey || identity.Name != "Work" {
+		t.Errorf("identity after --key = %+v", identity)
+	}
+
+	// So does switching to a PAT, which doesn't use the key
+	os.Remove(newKey)
+	withStdin(t, "work-pat\n\n\n\n")
+	parseFlags(t, editIdentityCmd, "--auth", "pat")
+	if err := editIdentity(editIdentityCmd, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if identity, _ := config.FindIdentityByAlias("work"); identity.AuthMethod != "pat" {
+		t.Errorf("auth after switching = %s, want pat", identity.AuthMethod)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/csawai/git-identity-switcher/internal/bundle"
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// bundlePasswordEnv supplies the bundle passphrase non-interactively
+const bundlePasswordEnv = "GITX_BUNDLE_PASSWORD"
+
+var (
+	exportOut            string
+	exportIncludeKeys    bool
+	exportIncludeSecrets bool
+)
+
+func init() {
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Bundle file to write (required)")
+	exportCmd.Flags().BoolVar(&exportIncludeKeys, "include-keys", true, "Include SSH private keys and certificates")
+	exportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "Include keychain secrets such as PATs")
+	exportCmd.MarkFlagRequired("out")
+	rootCmd.AddCommand(exportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [alias...]",
+	Short: "Export identities to an encrypted bundle",
+	Long: `Write identities (all of them unless aliases are given), their SSH keys and,
+with --include-secrets, their keychain secrets to a single file encrypted with a
+passphrase. Restore it on another machine with 'gitx import <bundle>'.
+The passphrase is read from $` + bundlePasswordEnv + ` or prompted for.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := exportIdentities(args); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// readBundlePassphrase returns $GITX_BUNDLE_PASSWORD or prompts, twice when confirm is set
+func readBundlePassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(bundlePasswordEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := keyring.TerminalPrompt("Bundle passphrase")
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase (set %s when not on a terminal): %w", bundlePasswordEnv, err)
+	}
+	if confirm {
+		again, err := keyring.TerminalPrompt("Repeat passphrase")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func exportIdentities(aliases []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	identities := cfg.Identities
+	if len(aliases) > 0 {
+		identities = nil
+		for _, alias := range aliases {
+			identity, err := config.FindIdentityByAlias(alias)
+			if err != nil {
+				return err
+			}
+			identities = append(identities, *identity)
+		}
+	}
+	if len(identities) == 0 {
+		return fmt.Errorf("no identities to export")
+	}
+
+	b := &bundle.Bundle{
+		Version:        bundle.FormatVersion,
+		CreatedAt:      time.Now(),
+		PinnedHostKeys: cfg.PinnedHostKeys,
+	}
+	for _, identity := range identities {
+		entry := bundle.Entry{Identity: identity}
+		// Rotation history points at files on this machine only
+		entry.Identity.KeyRotations = nil
+
+		if exportIncludeKeys && identity.SSHKeyPath != "" {
+			key, err := os.ReadFile(identity.SSHKeyPath)
+			if err != nil {
+				return fmt.Errorf("%s: failed to read SSH key: %w", identity.Alias, err)
+			}
+			entry.SSHKey = string(key)
+			if pub, err := os.ReadFile(identity.SSHKeyPath + ".pub"); err == nil {
+				entry.SSHPublicKey = string(pub)
+			}
+			if identity.SSHCertPath != "" {
+				if cert, err := os.ReadFile(identity.SSHCertPath); err == nil {
+					entry.SSHCert = string(cert)
+				}
+			}
+		}
+
+		if exportIncludeSecrets {
+			records, err := secretStore.List(identity.Alias)
+			if err != nil {
+				return err
+			}
+			for _, record := range records {
+				value, err := secretStore.Get(identity.Alias, record.Key)
+				if err != nil {
+					return fmt.Errorf("%s: failed to read secret %s: %w", identity.Alias, record.Key, err)
+				}
+				if entry.Secrets == nil {
+					entry.Secrets = map[string]string{}
+				}
+				entry.Secrets[record.Key] = value
+			}
+		}
+		b.Identities = append(b.Identities, entry)
+	}
+
+	passphrase, err := readBundlePassphrase(true)
+	if err != nil {
+		return err
+	}
+	data, err := bundle.Seal(b, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(exportOut, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for _, entry := range b.Identities {
+		details := ""
+		if entry.SSHKey != "" {
+			details += " +key"
+		}
+		if len(entry.Secrets) > 0 {
+			details += fmt.Sprintf(" +%d secret(s)", len(entry.Secrets))
+		}
+		fmt.Printf("  %s %s%s\n", ui.SuccessText.Render("✓"), entry.Identity.Alias, ui.MutedText.Render(details))
+	}
+	fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Exported %d %s to %s", len(b.Identities), plural(len(b.Identities), "identity", "identities"), exportOut)))
+	return nil
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// overrideSection is the git config section where bind records the keys it set
+// from an identity's git_config, and their previous values. "http.proxy" is
+// recorded as gitx-override.http.proxy.applied, holding the value bind set,
+// plus .previous when the repository had a value of its own.
+const overrideSection = "gitx-override"
+
+// sortedKeys returns the keys of overrides in a stable order
+func sortedKeys(overrides map[string]string) []string {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appliedOverrides returns the keys bind has set in the current repository
+func appliedOverrides() []string {
+	output, err := exec.Command("git", "config", "--local", "--null", "--get-regexp", `^`+overrideSection+`\..*\.applied$`).Output()
+	if err != nil {
+		return nil // exit status 1: nothing recorded
+	}
+	var keys []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		name, _, _ := strings.Cut(entry, "\n")
+		if name == "" {
+			continue
+		}
+		keys = append(keys, strings.TrimSuffix(strings.TrimPrefix(name, overrideSection+"."), ".applied"))
+	}
+	return keys
+}
+
+// applyGitConfigOverrides sets overrides in the current repository, saving the
+// values they replace, and reverts keys an earlier bind set that overrides no
+// longer has
+func applyGitConfigOverrides(overrides map[string]string) error {
+	if err := revertGitConfigOverrides(overrides); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(overrides) {
+		record := overrideSection + "." + key
+		if _, err := getGitConfigLocal(record + ".applied"); err != nil {
+			// First time: remember what the repository had, if anything
+			if previous, err := getGitConfigLocal(key); err == nil {
+				if err := setGitConfig(record+".previous", previous); err != nil {
+					return fmt.Errorf("failed to record %s: %w", key, err)
+				}
+			}
+		}
+		if err := setGitConfig(record+".applied", overrides[key]); err != nil {
+			return fmt.Errorf("failed to record %s: %w", key, err)
+		}
+		if err := setGitConfig(key, overrides[key]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// revertGitConfigOverrides restores the previous value of every recorded key
+// not in keep, or unsets it when the repository had none. A key changed since
+// bind set it keeps the user's value.
+func revertGitConfigOverrides(keep map[string]string) error {
+	for _, key := range appliedOverrides() {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		record := overrideSection + "." + key
+		if !changedSinceApplied(key) {
+			if previous, err := getGitConfigLocal(record + ".previous"); err == nil {
+				if err := setGitConfig(key, previous); err != nil {
+					return fmt.Errorf("failed to restore %s: %w", key, err)
+				}
+			} else {
+				exec.Command("git", "config", "--local", "--unset-all", key).Run()
+			}
+		}
+		if err := exec.Command("git", "config", "--local", "--remove-section", record).Run(); err != nil {
+			return fmt.Errorf("failed to forget %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// changedSinceApplied reports whether key no longer has the value bind set
+func changedSinceApplied(key string) bool {
+	current, err := getGitConfigLocal(key)
+	if err != nil {
+		return false // unset: nothing of the user's to keep
+	}
+	applied, _ := getGitConfigLocal(overrideSection + "." + key + ".applied")
+	return current != applied
+}
+
+// printGitConfigOverrides shows what applyGitConfigOverrides would change
+func printGitConfigOverrides(overrides map[string]string) {
+	for _, key := range appliedOverrides() {
+		if _, ok := overrides[key]; ok {
+			continue
+		}
+		current, _ := getGitConfigLocal(key)
+		if changedSinceApplied(key) {
+			fmt.Printf("  %s: '%s' (changed since bind, kept)\n", key, current)
+			continue
+		}
+		previous, _ := getGitConfigLocal(overrideSection + "." + key + ".previous")
+		fmt.Printf("  %s: '%s' -> '%s' (restored)\n", key, current, previous)
+	}
+	for _, key := range sortedKeys(overrides) {
+		current, _ := getGitConfigLocal(key)
+		fmt.Printf("  %s: '%s' -> '%s'\n", key, current, overrides[key])
+	}
+}
+
+// withGitConfig returns overrides with the "key=value" entries of set added
+// and the keys in unset removed
+func withGitConfig(overrides map[string]string, set, unset []string) (map[string]string, error) {
+	result := map[string]string{}
+	for key, value := range overrides {
+		result[key] = value
+	}
+	for _, entry := range set {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid --git-config %q: use key=value", entry)
+		}
+		result[strings.TrimSpace(key)] = value
+	}
+	for _, key := range unset {
+		delete(result, strings.TrimSpace(key))
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
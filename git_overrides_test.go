@@ -0,0 +1,161 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+// inTestRepo runs the test from a new git repository
+func inTestRepo(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	oldDir, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(oldDir) })
+	os.Chdir(dir)
+	if err := exec.Command("git", "init", "-q").Run(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wantGitConfig checks the repository's local value of each key; "" means unset
+func wantGitConfig(t *testing.T, want map[string]string) {
+	t.Helper()
+	for key, value := range want {
+		got, err := getGitConfigLocal(key)
+		if value == "" && err == nil {
+			t.Errorf("%s = %q, want it unset", key, got)
+		} else if value != "" && got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestGitConfigOverridesBindUnbind(t *testing.T) {
+	inTestRepo(t)
+	setGitConfig("pull.rebase", "false")
+
+	if err := applyGitConfigOverrides(map[string]string{"pull.rebase": "true", "http.proxy": "http://proxy:3128"}); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{"pull.rebase": "true", "http.proxy": "http://proxy:3128"})
+
+	// Binding again keeps the value the repository had before the first bind
+	if err := applyGitConfigOverrides(map[string]string{"pull.rebase": "true", "http.proxy": "http://proxy:3128"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := revertGitConfigOverrides(nil); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{"pull.rebase": "false", "http.proxy": ""})
+	if keys := appliedOverrides(); len(keys) != 0 {
+		t.Errorf("records left after unbind: %v", keys)
+	}
+}
+
+func TestGitConfigOverridesRebind(t *testing.T) {
+	inTestRepo(t)
+	setGitConfig("core.autocrlf", "false")
+
+	// Identity A, then identity B sharing one key
+	if err := applyGitConfigOverrides(map[string]string{"http.proxy": "http://a:3128", "core.autocrlf": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyGitConfigOverrides(map[string]string{"core.autocrlf": "input", "pull.ff": "only"}); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{"http.proxy": "", "core.autocrlf": "input", "pull.ff": "only"})
+
+	if err := revertGitConfigOverrides(nil); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{"core.autocrlf": "false", "pull.ff": ""})
+}
+
+func TestGitConfigOverridesChangedAfterBind(t *testing.T) {
+	inTestRepo(t)
+	setGitConfig("http.proxy", "http://old:3128")
+
+	if err := applyGitConfigOverrides(map[string]string{"http.proxy": "http://proxy:3128", "pull.rebase": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	setGitConfig("http.proxy", "http://mine:3128")
+	exec.Command("git", "config", "--local", "--unset", "pull.rebase").Run()
+
+	if err := revertGitConfigOverrides(nil); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{"http.proxy": "http://mine:3128", "pull.rebase": ""})
+	if keys := appliedOverrides(); len(keys) != 0 {
+		t.Errorf("records left after unbind: %v", keys)
+	}
+}
+
+func TestBindAndUnbindRepository(t *testing.T) {
+	work := &config.Identity{
+		Alias: "work", Name: "Work", Email: "work@example.com", Emails: []string{"w@example.org"},
+		GitHubUser: "octo", AuthMethod: "pat", GitConfig: map[string]string{"pull.rebase": "true", "http.proxy": "http://proxy:3128"},
+	}
+	usePATIdentities(t, *work)
+	inTestRepo(t)
+	exec.Command("git", "remote", "add", "origin", "git@github.com:org/repo.git").Run()
+	setGitConfig("pull.rebase", "false")
+
+	if err := applyIdentity(work); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{
+		"user.name": "Work", "user.email": "work@example.com", "gitx.bound": "work",
+		"pull.rebase": "true", "http.proxy": "http://proxy:3128",
+		"remote.origin.url": "https://github.com/org/repo.git",
+	})
+	if emails := gitConfigAll("gitx.email"); strings.Join(emails, ",") != "work@example.com,w@example.org" {
+		t.Errorf("gitx.email = %v", emails)
+	}
+	if helpers := gitConfigAll("credential.helper"); len(helpers) != 2 || helpers[0] != "" || !isGitxCredentialHelper(helpers[1]) {
+		t.Errorf("credential.helper = %q, want a reset and the gitx helper", helpers)
+	}
+
+	// Binding an ssh identity instead swaps the overrides and drops the helper
+	personal := &config.Identity{
+		Alias: "personal", Name: "Personal", Email: "me@example.com", GitHubUser: "cat",
+		AuthMethod: "ssh", SSHHostAlias: "github.com-personal", GitConfig: map[string]string{"pull.ff": "only"},
+	}
+	if err := applyIdentity(personal); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{
+		"gitx.bound": "personal", "pull.rebase": "false", "http.proxy": "", "pull.ff": "only",
+		"remote.origin.url": "git@github.com-personal:org/repo.git",
+	})
+	if helpers := gitConfigAll("credential.helper"); len(helpers) != 0 {
+		t.Errorf("credential.helper = %q after binding an ssh identity", helpers)
+	}
+
+	if err := applyIdentity(work); err != nil {
+		t.Fatal(err)
+	}
+	if err := unbind(); err != nil {
+		t.Fatal(err)
+	}
+	wantGitConfig(t, map[string]string{
+		"user.name": "", "user.email": "", "gitx.bound": "", "gitx.email": "",
+		"pull.rebase": "false", "http.proxy": "", "pull.ff": "", "credential.helper": "",
+	})
+	if keys := appliedOverrides(); len(keys) != 0 {
+		t.Errorf("records left after unbind: %v", keys)
+	}
+}
+
+// gitConfigAll returns every local value of key
+func gitConfigAll(key string) []string {
+	output, err := exec.Command("git", "config", "--local", "--get-all", key).Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+}
@@ -73,7 +73,28 @@ func installHook() error {
 	// Write hook
 	hookContent := `#!/bin/sh
 # gitx pre-push hook
-# Blocks push if repository is not bound to an identity
+# Blocks push if repository is not bound to an identity, or if pushed commits
+# are authored with an email the bound identity doesn't use
+
+# Check pushed commits against the emails recorded by 'gitx bind'
+allowed=$(git config --local --get-all gitx.email 2>/dev/null)
+if [ -n "$allowed" ]; then
+  while read local_ref local_sha remote_ref remote_sha; do
+    case "$local_sha" in *[!0]*) ;; *) continue ;; esac
+    case "$remote_sha" in
+      *[!0]*) range="$remote_sha..$local_sha" ;;
+      *) range="$local_sha --not --remotes" ;;
+    esac
+    for author in $(git log --format=%ae $range | sort -u); do
+      if ! echo "$allowed" | grep -qixF "$author"; then
+        bound=$(git config --local --get gitx.bound)
+        echo "Error: $local_ref has commits by $author, which is not an email of identity '$bound'."
+        echo "Run 'gitx audit commits' to list them, or allow the email with 'gitx edit identity $bound --add-email $author --apply'."
+        exit 1
+      fi
+    done
+  done
+fi
 
 # Check if remote uses gitx host alias
 remote=$(git remote get-url origin 2>/dev/null)
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importMove   bool
+	importDryRun bool
+)
+
+func init() {
+	importSSHCmd.Flags().BoolVar(&importMove, "move", false, "Move imported Host entries into the gitx managed block")
+	importSSHCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "List importable entries without making changes")
+	importCmd.AddCommand(importSSHCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [bundle]",
+	Short: "Import identities from a gitx bundle or existing configuration",
+	Long: `Restore identities from a bundle written by 'gitx export', or import existing
+SSH configuration with 'gitx import ssh'.
+
+When a bundle identity's alias already exists, --on-conflict decides what happens:
+skip it, rename the imported identity, or overwrite the local one. Without the flag
+gitx asks for each conflict.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Help()
+			return
+		}
+		if err := importBundle(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var importSSHCmd = &cobra.Command{
+	Use:   "ssh",
+	Short: "Import Host entries from ~/.ssh/config as identities",
+	Long: `List Host entries in ~/.ssh/config that point at a git host and are not managed by gitx,
+and turn the selected ones into identities that reuse their existing keys.
+With --move the entries are moved into the gitx managed block; otherwise they are
+left where they are and gitx will not write its own entry for them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := importSSH(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func importSSH() error {
+	entries, err := ssh.ListUnmanagedGitHosts()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Skip hosts that already belong to an identity
+	var candidates []ssh.HostEntry
+	for _, entry := range entries {
+		known := false
+		for _, id := range cfg.Identities {
+			if id.SSHHostAlias == entry.Host {
+				known = true
+				break
+			}
+		}
+		if !known {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println(ui.InfoBox.Render("No importable git Host entries found in your SSH config."))
+		return nil
+	}
+
+	fmt.Println(ui.HeaderStyle.Render("🔍 Git Host entries in ~/.ssh/config"))
+	for i, entry := range candidates {
+		hostName := entry.HostName
+		if hostName == "" {
+			hostName = entry.Host
+		}
+		fmt.Printf("  %d) %s → %s  %s\n", i+1, ui.InfoText.Render(entry.Host), hostName, ui.MutedText.Render(entry.IdentityFile))
+	}
+	fmt.Println()
+
+	if importDryRun {
+		fmt.Println("[DRY RUN] No changes were made.")
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Select entries to import (e.g. 1,3 or 'all'): ")
+	selection, _ := reader.ReadString('\n')
+	selected, err := parseSelection(strings.TrimSpace(selection), len(candidates))
+	if err != nil {
+		return err
+	}
+
+	for _, idx := range selected {
+		if err := importHostEntry(reader, candidates[idx]); err != nil {
+			return fmt.Errorf("%s: %w", candidates[idx].Host, err)
+		}
+	}
+	return nil
+}
+
+// parseSelection turns "1,3" or "all" into zero-based indexes
+func parseSelection(selection string, count int) ([]int, error) {
+	if selection == "" {
+		return nil, fmt.Errorf("no entries selected")
+	}
+	var indexes []int
+	if strings.EqualFold(selection, "all") {
+		for i := 0; i < count; i++ {
+			indexes = append(indexes, i)
+		}
+		return indexes, nil
+	}
+	for _, part := range strings.Split(selection, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > count {
+			return nil, fmt.Errorf("invalid selection: %s", part)
+		}
+		indexes = append(indexes, n-1)
+	}
+	return indexes, nil
+}
+
+// moveHostEntry replaces the user's Host block with a managed entry. The
+// managed entry is written before the block is deleted so the host never
+// disappears, and taken out again if the block can't be deleted.
+func moveHostEntry(entry ssh.HostEntry, keyPath string) error {
+	if err := ensureKnownHosts(); err != nil {
+		return fmt.Errorf("failed to seed known_hosts: %w", err)
+	}
+	if err := ssh.UpsertSSHConfigEntry(ssh.SSHIdentity{
+		HostAlias: entry.Host,
+		HostName:  entry.HostName,
+		KeyPath:   keyPath,
+		Options:   entry.Options,
+	}); err != nil {
+		return fmt.Errorf("failed to add managed SSH entry: %w", err)
+	}
+	if err := ssh.RemoveHostEntry(entry.Host); err != nil {
+		ssh.RemoveSSHConfigEntry(entry.Host)
+		return fmt.Errorf("failed to remove original Host entry: %w", err)
+	}
+	return nil
+}
+
+func importHostEntry(reader *bufio.Reader, entry ssh.HostEntry) error {
+	keyPath, err := ssh.ExpandPath(entry.IdentityFile)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(keyPath); err != nil {
+		return fmt.Errorf("key file not found: %s", keyPath)
+	}
+	if unmovable := entry.UnmovableOptions(); importMove && len(unmovable) > 0 {
+		return fmt.Errorf("cannot move Host %s into the gitx block, which can't express %s; import it without --move", entry.Host, strings.Join(unmovable, ", "))
+	}
+
+	fmt.Println()
+	fmt.Println(ui.HeaderStyle.Render("📥 Importing " + entry.Host))
+
+	defaultAlias := entry.Host
+	for _, prefix := range []string{"github.com-", "github-", "gitlab.com-", "gitlab-"} {
+		defaultAlias = strings.TrimPrefix(defaultAlias, prefix)
+	}
+
+	prompt := func(label, def string) string {
+		if def != "" {
+			fmt.Printf("%s [%s]: ", ui.InfoText.Render(label), def)
+		} else {
+			fmt.Printf("%s: ", ui.InfoText.Render(label))
+		}
+		value, _ := reader.ReadString('\n')
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return def
+		}
+		return value
+	}
+
+	identity := config.Identity{
+		Alias:        prompt("📝 Identity alias", defaultAlias),
+		Name:         prompt("👤 Name", ""),
+		Email:        prompt("📧 Email", ""),
+		GitHubUser:   prompt("🐙 GitHub username", ""),
+		AuthMethod:   "ssh",
+		SSHKeyPath:   keyPath,
+		SSHHostAlias: entry.Host,
+	}
+	if err := identity.Validate(); err != nil {
+		return err
+	}
+
+	// Add the identity first: if that fails (e.g. the alias is taken), the
+	// user's Host block hasn't been touched yet
+	identity.ExternalHostEntry = !importMove
+	if err := config.AddIdentity(identity); err != nil {
+		return err
+	}
+
+	if importMove {
+		if err := moveHostEntry(entry, keyPath); err != nil {
+			if removeErr := config.RemoveIdentity(identity.Alias); removeErr != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to remove identity again: "+removeErr.Error()))
+			}
+			return err
+		}
+	}
+
+	fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Imported '%s' (key: %s)", identity.Alias, keyPath)))
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/csawai/git-identity-switcher/internal/bundle"
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+)
+
+var (
+	bundleOnConflict string
+	bundleDryRun     bool
+)
+
+func init() {
+	importCmd.Flags().StringVar(&bundleOnConflict, "on-conflict", "", "What to do when an alias exists: skip, rename or overwrite")
+	importCmd.Flags().BoolVar(&bundleDryRun, "dry-run", false, "Show what would be imported without making changes")
+}
+
+func importBundle(path string) error {
+	switch bundleOnConflict {
+	case "", "skip", "rename", "overwrite":
+	default:
+		return fmt.Errorf("invalid --on-conflict %q (use skip, rename or overwrite)", bundleOnConflict)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+	passphrase, err := readBundlePassphrase(false)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.Open(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.PinnedHostKeys) == 0 && len(b.PinnedHostKeys) > 0 && !bundleDryRun {
+		cfg.PinnedHostKeys = b.PinnedHostKeys
+		if err := config.UpdateConfig(func(stored *config.Config) error {
+			if len(stored.PinnedHostKeys) == 0 {
+				stored.PinnedHostKeys = b.PinnedHostKeys
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	taken := map[string]bool{}
+	hosts := map[string]string{} // SSH host alias -> identity alias
+	for _, id := range cfg.Identities {
+		taken[id.Alias] = true
+		if id.SSHHostAlias != "" {
+			hosts[id.SSHHostAlias] = id.Alias
+		}
+	}
+
+	imported := 0
+	for _, entry := range b.Identities {
+		alias := entry.Identity.Alias
+		action := "add"
+		if taken[alias] {
+			action = bundleOnConflict
+			if action == "" {
+				action = promptConflict(reader, alias)
+			}
+		}
+
+		switch action {
+		case "skip":
+			fmt.Printf("  %s %s %s\n", ui.MutedText.Render("-"), alias, ui.MutedText.Render("(exists, skipped)"))
+			continue
+		case "rename":
+			newAlias := alias
+			for i := 2; taken[newAlias]; i++ {
+				newAlias = fmt.Sprintf("%s-%d", alias, i)
+			}
+			renameBundleEntry(&entry, newAlias)
+		}
+
+		if err := validateBundleEntry(entry, hosts); err != nil {
+			return fmt.Errorf("%s: %w", entry.Identity.Alias, err)
+		}
+		if host := entry.Identity.SSHHostAlias; host != "" {
+			hosts[host] = entry.Identity.Alias
+		}
+		if bundleDryRun {
+			fmt.Printf("  [DRY RUN] %s %s\n", action, describeBundleEntry(entry))
+			taken[entry.Identity.Alias] = true
+			continue
+		}
+		if err := importBundleEntry(entry, action == "overwrite"); err != nil {
+			return fmt.Errorf("%s: %w", entry.Identity.Alias, err)
+		}
+		taken[entry.Identity.Alias] = true
+		imported++
+		fmt.Printf("  %s %s\n", ui.SuccessText.Render("✓"), describeBundleEntry(entry))
+	}
+
+	if !bundleDryRun {
+		fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Imported %d %s from %s", imported, plural(imported, "identity", "identities"), path)))
+	}
+	return nil
+}
+
+func promptConflict(reader *bufio.Reader, alias string) string {
+	fmt.Printf("Identity '%s' already exists. [s]kip, [r]ename or [o]verwrite? [s]: ", alias)
+	response, _ := reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "r", "rename":
+		return "rename"
+	case "o", "overwrite":
+		return "overwrite"
+	}
+	return "skip"
+}
+
+// renameBundleEntry gives an imported identity a new alias. A host alias gitx
+// manages gets the default one for the new alias, as the old one most likely
+// belongs to the identity being renamed around; a Host block of the user's
+// own keeps its name.
+func renameBundleEntry(entry *bundle.Entry, alias string) {
+	external := entry.Identity.ExternalHostEntry && entry.SSHKey == ""
+	if entry.Identity.SSHHostAlias != "" && !external {
+		entry.Identity.SSHHostAlias = "github.com-" + alias
+	}
+	entry.Identity.Alias = alias
+}
+
+// validateBundleEntry checks the entry's identity, and that its SSH host alias
+// isn't used by another identity in hosts (host alias -> identity alias). A
+// missing SSH key is left out: the bundle may carry the key, and a key that is
+// only on the source machine is warned about on import.
+func validateBundleEntry(entry bundle.Entry, hosts map[string]string) error {
+	var problems []error
+	for _, problem := range entry.Identity.Problems() {
+		if !errors.Is(problem, config.ErrKeyNotFound) {
+			problems = append(problems, problem)
+		}
+	}
+	host := entry.Identity.SSHHostAlias
+	if other, ok := hosts[host]; ok && host != "" && other != entry.Identity.Alias {
+		problems = append(problems, fmt.Errorf("SSH host alias %s is already used by '%s'", host, other))
+	}
+	return errors.Join(problems...)
+}
+
+func describeBundleEntry(entry bundle.Entry) string {
+	details := []string{entry.Identity.AuthMethod}
+	if entry.SSHKey != "" {
+		details = append(details, "key")
+	}
+	if len(entry.Secrets) > 0 {
+		details = append(details, fmt.Sprintf("%d secret(s)", len(entry.Secrets)))
+	}
+	return entry.Identity.Alias + " " + ui.MutedText.Render("("+strings.Join(details, ", ")+")")
+}
+
+func importBundleEntry(entry bundle.Entry, overwrite bool) error {
+	identity := entry.Identity
+
+	var previous *config.Identity
+	if overwrite {
+		previous, _ = config.FindIdentityByAlias(identity.Alias)
+	}
+
+	if entry.SSHKey != "" {
+		keyPath, err := importedKeyPath(identity.Alias, entry.SSHKey, overwrite)
+		if err != nil {
+			return err
+		}
+		if err := writeKeyFile(keyPath, entry.SSHKey, 0600, overwrite); err != nil {
+			return err
+		}
+		if entry.SSHPublicKey != "" {
+			if err := writeKeyFile(keyPath+".pub", entry.SSHPublicKey, 0644, true); err != nil {
+				return err
+			}
+		}
+		identity.SSHKeyPath = keyPath
+		identity.SSHCertPath = ""
+		if entry.SSHCert != "" {
+			certPath := ssh.CertificatePath(keyPath)
+			if err := writeKeyFile(certPath, entry.SSHCert, 0644, true); err != nil {
+				return err
+			}
+			identity.SSHCertPath = certPath
+		}
+		// The source machine's own Host block doesn't exist here; gitx manages it now
+		identity.ExternalHostEntry = false
+	} else if identity.SSHKeyPath != "" {
+		if _, err := os.Stat(identity.SSHKeyPath); err != nil {
+			fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  %s: SSH key %s is not on this machine", identity.Alias, identity.SSHKeyPath)))
+		}
+	}
+
+	if identity.AuthMethod == "ssh" && identity.SSHHostAlias != "" && identity.SSHKeyPath != "" && !identity.ExternalHostEntry {
+		if err := ensureKnownHosts(); err != nil {
+			return fmt.Errorf("failed to seed known_hosts: %w", err)
+		}
+		hostEntry := ssh.SSHIdentity{
+			HostAlias:       identity.SSHHostAlias,
+			KeyPath:         identity.SSHKeyPath,
+			CertificateFile: identity.SSHCertPath,
+		}
+		if identity.IsolatedAgent {
+			if socket, err := identityAgentSocket(identity.Alias); err == nil {
+				hostEntry.IdentityAgent = socket
+			}
+		}
+		if err := ssh.UpsertSSHConfigEntry(hostEntry); err != nil {
+			return fmt.Errorf("failed to update SSH config: %w", err)
+		}
+		if previous != nil && previous.SSHHostAlias != "" && previous.SSHHostAlias != identity.SSHHostAlias && !previous.ExternalHostEntry {
+			if err := ssh.RemoveSSHConfigEntry(previous.SSHHostAlias); err != nil {
+				return fmt.Errorf("failed to remove old SSH config entry: %w", err)
+			}
+		}
+	}
+
+	if previous != nil {
+		if err := config.UpdateIdentity(identity); err != nil {
+			return err
+		}
+	} else if err := config.AddIdentity(identity); err != nil {
+		return err
+	}
+
+	for key, value := range entry.Secrets {
+		if err := secretStore.Store(identity.Alias, key, value); err != nil {
+			return fmt.Errorf("failed to store secret %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// writeKeyFile writes key material, refusing to clobber a different existing file
+// unless overwrite is set. An overwritten file is first moved aside to a
+// timestamped backup, as it may be a key still registered on GitHub.
+func writeKeyFile(path, content string, mode os.FileMode, overwrite bool) error {
+	if existing, err := os.ReadFile(path); err == nil {
+		if string(existing) == content {
+			return nil
+		}
+		if !overwrite {
+			return fmt.Errorf("%s already exists with different content", path)
+		}
+		backupPath := path + ".gitx.backup." + time.Now().Format("20060102-150405")
+		if err := os.Rename(path, backupPath); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		fmt.Println(ui.MutedText.Render("  previous " + filepath.Base(path) + " kept as " + backupPath))
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// importedKeyPath picks where an imported identity's key goes: gitx_<alias>,
+// or a suffixed name when that file holds a different key
+func importedKeyPath(alias, key string, overwrite bool) (string, error) {
+	keyPath, err := ssh.KeyPath(alias)
+	if err != nil {
+		return "", err
+	}
+	if overwrite {
+		return keyPath, nil
+	}
+	candidate := keyPath
+	for i := 2; ; i++ {
+		existing, err := os.ReadFile(candidate)
+		if os.IsNotExist(err) || (err == nil && string(existing) == key) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_imported%d", keyPath, i)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/bundle"
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+func TestWriteKeyFileKeepsOverwrittenKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitx_work")
+	if err := os.WriteFile(path, []byte("old key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeKeyFile(path, "new key", 0600, false); err == nil {
+		t.Error("expected a different existing key to be refused without overwrite")
+	}
+	if err := writeKeyFile(path, "new key", 0600, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "new key" {
+		t.Errorf("key = %q, want the imported one", data)
+	}
+	backups, _ := filepath.Glob(path + ".gitx.backup.*")
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, found %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != "old key" {
+		t.Errorf("backup = %q, want the previous key", data)
+	}
+}
+
+func TestRenamedBundleEntryHostAlias(t *testing.T) {
+	hosts := map[string]string{"github.com-work": "work", "gh-work": "work"}
+	for _, host := range []string{"github.com-work", "gh-work"} {
+		entry := bundle.Entry{Identity: config.Identity{Alias: "work", Name: "Work", Email: "work@example.com", GitHubUser: "octo", AuthMethod: "ssh", SSHHostAlias: host}}
+		if err := validateBundleEntry(entry, hosts); err != nil {
+			t.Errorf("%s: overwriting the owner's entry was refused: %v", host, err)
+		}
+
+		renameBundleEntry(&entry, "work-2")
+		if entry.Identity.SSHHostAlias != "github.com-work-2" {
+			t.Errorf("%s: renamed entry's host alias = %s", host, entry.Identity.SSHHostAlias)
+		}
+		if err := validateBundleEntry(entry, hosts); err != nil {
+			t.Errorf("%s: renamed entry refused: %v", host, err)
+		}
+	}
+
+	// A Host block of the user's own keeps its name, and can't be shared
+	entry := bundle.Entry{Identity: config.Identity{Alias: "work", Name: "Work", Email: "work@example.com", GitHubUser: "octo", AuthMethod: "ssh", SSHHostAlias: "gh-work", ExternalHostEntry: true}}
+	renameBundleEntry(&entry, "work-2")
+	err := validateBundleEntry(entry, hosts)
+	if entry.Identity.SSHHostAlias != "gh-work" || err == nil || !strings.Contains(err.Error(), "already used by 'work'") {
+		t.Errorf("external host alias %s: %v", entry.Identity.SSHHostAlias, err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package bundle reads and writes passphrase-encrypted gitx export files
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jose "github.com/dvsekhvalnov/jose2go"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+// FormatVersion is the bundle layout written by this version of gitx
+const FormatVersion = 1
+
+// header marks the first line of a bundle file; the JWE token follows
+const header = "gitx-bundle v1"
+
+// Bundle is the decrypted content of an export file
+type Bundle struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Identities []Entry   `json:"identities"`
+	// PinnedHostKeys carries the exporting machine's pinned host keys, if customised
+	PinnedHostKeys map[string][]string `json:"pinned_host_keys,omitempty"`
+}
+
+// Entry is one exported identity with its optional key material and secrets
+type Entry struct {
+	Identity     config.Identity   `json:"identity"`
+	SSHKey       string            `json:"ssh_key,omitempty"` // private key file contents
+	SSHPublicKey string            `json:"ssh_public_key,omitempty"`
+	SSHCert      string            `json:"ssh_cert,omitempty"`
+	Secrets      map[string]string `json:"secrets,omitempty"` // keychain key -> value
+}
+
+// Seal encrypts b with passphrase (PBES2 key wrapping, AES-256-GCM content encryption)
+func Seal(b *Bundle, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	token, err := jose.EncryptBytes(payload, jose.PBES2_HS512_A256KW, jose.A256GCM, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return []byte(header + "\n" + token + "\n"), nil
+}
+
+// Open decrypts a bundle written by Seal
+func Open(data []byte, passphrase string) (*Bundle, error) {
+	first, token, ok := strings.Cut(string(data), "\n")
+	if !ok || strings.TrimSpace(first) != header {
+		return nil, fmt.Errorf("not a gitx bundle")
+	}
+	payload, _, err := jose.DecodeBytes(strings.TrimSpace(token), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bundle (wrong passphrase?)")
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(payload, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	if b.Version > FormatVersion {
+		return nil, fmt.Errorf("bundle format %d is newer than this gitx supports (%d)", b.Version, FormatVersion)
+	}
+	return &b, nil
+}
@@ -0,0 +1,40 @@
+package bundle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+func TestSealOpen(t *testing.T) {
+	b := &Bundle{
+		Version: FormatVersion,
+		Identities: []Entry{{
+			Identity: config.Identity{Alias: "work", AuthMethod: "pat"},
+			Secrets:  map[string]string{"pat": "ghp_secret"},
+		}},
+	}
+	data, err := Seal(b, "correct horse")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_secret") || strings.Contains(string(data), "work") {
+		t.Fatal("bundle contains plaintext")
+	}
+
+	opened, err := Open(data, "correct horse")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(opened.Identities) != 1 || opened.Identities[0].Secrets["pat"] != "ghp_secret" {
+		t.Errorf("round trip mismatch: %+v", opened)
+	}
+
+	if _, err := Open(data, "wrong"); err == nil {
+		t.Error("expected wrong passphrase to fail")
+	}
+	if _, err := Open([]byte("hello\nworld"), "correct horse"); err == nil {
+		t.Error("expected non-bundle to fail")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 const (
@@ -21,30 +23,206 @@ type Identity struct {
 	SSHKeyPath   string `json:"ssh_key_path,omitempty"`
 	AuthMethod   string `json:"auth_method"` // "ssh" or "pat"
 	SSHHostAlias string `json:"ssh_host_alias,omitempty"`
+	SSHCertPath  string `json:"ssh_cert_path,omitempty"` // CA-signed certificate for SSHKeyPath
+	// ExternalHostEntry means SSHHostAlias is a Host block the user maintains
+	// outside the gitx managed block, so gitx must not write its own entry
+	ExternalHostEntry bool `json:"external_host_entry,omitempty"`
+	// IsolatedAgent runs a dedicated ssh-agent holding only this identity's key
+	IsolatedAgent bool `json:"isolated_agent,omitempty"`
+	// KeyRotations records past SSH key rotations, newest last
+	KeyRotations []KeyRotation `json:"key_rotations,omitempty"`
+	// PAT describes the personal access token of a "pat" identity
+	PAT *PATInfo `json:"pat,omitempty"`
+	// Emails are further addresses the identity commits with. Email stays the
+	// primary one, which bind sets as user.email.
+	Emails []string `json:"emails,omitempty"`
+	// GitConfig holds extra git config keys bind sets in the repository, such
+	// as pull.rebase or http.proxy
+	GitConfig map[string]string `json:"git_config,omitempty"`
+}
+
+// AllowedEmails returns the primary email followed by the additional ones
+func (i *Identity) AllowedEmails() []string {
+	return append([]string{i.Email}, i.Emails...)
+}
+
+// AllowsEmail reports whether email is one of the identity's emails, ignoring case
+func (i *Identity) AllowsEmail(email string) bool {
+	for _, allowed := range i.AllowedEmails() {
+		if strings.EqualFold(allowed, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// PATInfo is the metadata gitx keeps about a personal access token. The token
+// itself lives in the keyring.
+type PATInfo struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil when the token doesn't expire
+	Scopes    []string   `json:"scopes,omitempty"`
+	Note      string     `json:"note,omitempty"`
+}
+
+// ExpiresWithin reports whether the token expires (or has expired) before now+d
+func (p *PATInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return p != nil && p.ExpiresAt != nil && p.ExpiresAt.Before(now.Add(d))
+}
+
+// Expired reports whether the token is past its expiry date at now
+func (p *PATInfo) Expired(now time.Time) bool {
+	return p != nil && p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// KeyRotation records a replaced SSH key that is kept until its grace period ends
+type KeyRotation struct {
+	RotatedAt      time.Time  `json:"rotated_at"`
+	OldKeyPath     string     `json:"old_key_path"`
+	OldFingerprint string     `json:"old_fingerprint"`
+	NewKeyPath     string     `json:"new_key_path"`
+	NewFingerprint string     `json:"new_fingerprint"`
+	GraceUntil     time.Time  `json:"grace_until"`
+	RetiredAt      *time.Time `json:"retired_at,omitempty"`
+}
+
+// PendingRotation returns the latest rotation whose old key has not been retired yet
+func (i *Identity) PendingRotation() *KeyRotation {
+	for j := len(i.KeyRotations) - 1; j >= 0; j-- {
+		if i.KeyRotations[j].RetiredAt == nil {
+			return &i.KeyRotations[j]
+		}
+	}
+	return nil
 }
 
 type Config struct {
+	// Version is the schema version of the file (see CurrentVersion)
+	Version    int        `json:"version"`
 	Identities []Identity `json:"identities"`
+	// PinnedHostKeys maps a hostname to its trusted "type base64" host keys;
+	// gitx's known_hosts file is seeded from it (GitHub's keys when empty)
+	PinnedHostKeys map[string][]string `json:"pinned_host_keys,omitempty"`
+	// KeyringBackend selects where secrets are stored (see keychain.Backends);
+	// empty picks the first available one, falling back to the encrypted file
+	KeyringBackend string `json:"keyring_backend,omitempty"`
+	// Secrets indexes the keyring entries stored per identity alias. Only the
+	// key names and timestamps live here, never the values.
+	Secrets map[string][]SecretRecord `json:"secrets,omitempty"`
+	// PATExpiryWarningDays is how many days ahead of a PAT's expiry gitx starts
+	// warning; 0 means DefaultPATExpiryWarningDays
+	PATExpiryWarningDays int `json:"pat_expiry_warning_days,omitempty"`
+	// BoundRepos lists the repositories gitx has bound, so identity-wide
+	// changes can be carried to them
+	BoundRepos []BoundRepo `json:"bound_repos,omitempty"`
 }
 
+// BoundRepo records a repository bound with 'gitx bind'
+type BoundRepo struct {
+	Path    string    `json:"path"` // top-level directory of the work tree
+	Alias   string    `json:"alias"`
+	BoundAt time.Time `json:"bound_at"`
+}
+
+// DefaultPATExpiryWarningDays is used when the config doesn't set PATExpiryWarningDays
+const DefaultPATExpiryWarningDays = 14
+
+// PATExpiryWarning returns how far ahead of expiry PAT warnings start
+func (c *Config) PATExpiryWarning() time.Duration {
+	days := c.PATExpiryWarningDays
+	if days <= 0 {
+		days = DefaultPATExpiryWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// SecretRecord notes that a secret exists in the keyring for an identity
+type SecretRecord struct {
+	Key       string    `json:"key"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConfigDirEnv names the environment variable that relocates the config directory
+const ConfigDirEnv = "GITX_CONFIG_DIR"
+
+// ConfigDirOverride, when set (from --config), takes precedence over ConfigDirEnv
+var ConfigDirOverride string
+
+// The config directory is, in order of precedence: ConfigDirOverride,
+// $GITX_CONFIG_DIR, $XDG_CONFIG_HOME/gitx and ~/.config/gitx. An existing
+// ~/.config/gitx still wins over an empty $XDG_CONFIG_HOME/gitx so setting
+// XDG_CONFIG_HOME doesn't hide identities created before it was honoured.
 var getConfigDirFunc = func() (string, error) {
+	if ConfigDirOverride != "" {
+		return filepath.Abs(ConfigDirOverride)
+	}
+	if dir := os.Getenv(ConfigDirEnv); dir != "" {
+		return filepath.Abs(dir)
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return filepath.Join(homeDir, ConfigDirName, GitxDirName), nil
+	legacy := filepath.Join(homeDir, ConfigDirName, GitxDirName)
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" && filepath.IsAbs(xdg) {
+		dir := filepath.Join(xdg, GitxDirName)
+		if dir != legacy && !exists(dir) && exists(legacy) {
+			return legacy, nil
+		}
+		return dir, nil
+	}
+	return legacy, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 func GetConfigDir() (string, error) {
 	return getConfigDirFunc()
 }
 
+// GetRuntimeDir returns the directory for gitx runtime files such as agent sockets.
+// It prefers $XDG_RUNTIME_DIR and falls back to a "run" directory in the config dir.
+func GetRuntimeDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, GitxDirName), nil
+	}
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "run"), nil
+}
+
+// GetKnownHostsPath returns the path of the gitx-managed known_hosts file
+func GetKnownHostsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "known_hosts"), nil
+}
+
+// GetKeyringDir returns the directory used by the encrypted file keyring backend
+func GetKeyringDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "keyring"), nil
+}
+
+// GetIdentitiesPath returns the identities file in use: identities.json,
+// .yaml/.yml or .toml, whichever exists (JSON when none does yet)
 func GetIdentitiesPath() (string, error) {
 	configDir, err := GetConfigDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, IdentitiesFile), nil
+	return findIdentitiesFile(configDir)
 }
 
 func LoadConfig() (*Config, error) {
@@ -53,45 +231,179 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// If file doesn't exist, return empty config
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return &Config{Identities: []Identity{}}, nil
+	if config, err := readCurrentConfig(path); config != nil || err != nil {
+		return config, err
 	}
 
+	// The file needs migrating or restoring from its backup. Do that under the
+	// lock, reading it again there: another gitx process may have written it
+	// (or already migrated it) in the meantime.
+	var config *Config
+	err = withConfigLock(func(path string) error {
+		loaded, dirty, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			if err := saveConfig(path, loaded); err != nil {
+				return fmt.Errorf("failed to save migrated config: %w", err)
+			}
+		}
+		config = loaded
+		return nil
+	})
+	return config, err
+}
+
+// readCurrentConfig reads the config at path without writing anything. It
+// returns nil when the file is corrupt or needs migrating, which loadConfig
+// handles.
+func readCurrentConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{Version: CurrentVersion, Identities: []Identity{}}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
+	data, err = toJSON(FormatOf(path), data)
+	if err != nil {
+		return nil, nil
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil
+	}
+	if version, err := configVersion(raw); err != nil || version != CurrentVersion {
+		return nil, nil
+	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if config.Identities == nil {
+		config.Identities = []Identity{}
+	}
+	return config, nil
+}
+
+// loadConfig reads the config at path. dirty reports that it was migrated or
+// recovered from the backup and should be written back.
+func loadConfig(path string) (config *Config, dirty bool, err error) {
+	// If file doesn't exist, return empty config
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Config{Version: CurrentVersion, Identities: []Identity{}}, false, nil
+	}
+
+	original, recovered, err := readConfigFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := toJSON(FormatOf(path), original)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	migrated, err := migrateConfig(path, original, data)
+	if err != nil {
+		return nil, false, err
+	}
+	if migrated != nil {
+		data = migrated
+	}
 
-	return &config, nil
+	config = &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, false, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if config.Identities == nil {
+		config.Identities = []Identity{}
+	}
+	return config, recovered || migrated != nil, nil
 }
 
+// SaveConfig writes config in place of the stored one. Callers that change a
+// freshly loaded config should prefer UpdateConfig, which holds the lock for
+// the whole read-modify-write.
 func SaveConfig(config *Config) error {
-	path, err := GetIdentitiesPath()
+	return withConfigLock(func(path string) error {
+		return saveConfig(path, config)
+	})
+}
+
+// UpdateConfig loads the config, applies fn and saves the result while holding
+// the config lock, so concurrent gitx processes can't lose each other's
+// changes. Nothing is written when fn returns an error.
+func UpdateConfig(fn func(*Config) error) error {
+	return withConfigLock(func(path string) error {
+		config, _, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(config); err != nil {
+			return err
+		}
+		return saveConfig(path, config)
+	})
+}
+
+// ConvertConfig rewrites the identities file in format. The file it replaces
+// is kept as <old name>.bak. It returns the old and new paths.
+func ConvertConfig(format Format) (from, to string, err error) {
+	err = withConfigLock(func(path string) error {
+		if FormatOf(path) == format {
+			return fmt.Errorf("%s is already %s", path, format)
+		}
+		config, _, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		from, to = path, filepath.Join(filepath.Dir(path), format.FileName())
+		if err := saveConfig(to, config); err != nil {
+			return err
+		}
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			return nil
+		}
+		if err := os.Rename(from, from+".bak"); err != nil {
+			os.Remove(to)
+			return fmt.Errorf("failed to retire %s: %w", from, err)
+		}
+		return nil
+	})
+	return from, to, err
+}
+
+// withConfigLock runs fn with the path of the identities file while holding
+// the config lock. The file is looked up under the lock, as a concurrent
+// ConvertConfig may change it.
+func withConfigLock(fn func(path string) error) error {
+	configDir, err := GetConfigDir()
 	if err != nil {
 		return err
 	}
-
-	configDir := filepath.Dir(path)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
+	unlock, err := lockConfig(configDir)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	path, err := findIdentitiesFile(configDir)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
 	}
+	return fn(path)
+}
 
-	if err := os.WriteFile(path, data, 0600); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+func saveConfig(path string, config *Config) error {
+	config.Version = CurrentVersion
+	previous, _ := os.ReadFile(path)
+	data, err := encodeConfig(config, FormatOf(path), previous)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return writeConfigFile(path, data)
 }
 
 func FindIdentityByAlias(alias string) (*Identity, error) {
@@ -110,43 +422,173 @@ func FindIdentityByAlias(alias string) (*Identity, error) {
 }
 
 func AddIdentity(identity Identity) error {
-	config, err := LoadConfig()
-	if err != nil {
-		return err
-	}
-
-	// Check if alias already exists
-	for _, existing := range config.Identities {
-		if existing.Alias == identity.Alias {
-			return fmt.Errorf("identity with alias '%s' already exists", identity.Alias)
+	return UpdateConfig(func(config *Config) error {
+		// Check if alias already exists
+		for _, existing := range config.Identities {
+			if existing.Alias == identity.Alias {
+				return fmt.Errorf("identity with alias '%s' already exists", identity.Alias)
+			}
 		}
-	}
 
-	config.Identities = append(config.Identities, identity)
-	return SaveConfig(config)
+		config.Identities = append(config.Identities, identity)
+		return nil
+	})
+}
+
+// UpdateIdentity replaces the stored identity that has the same alias
+func UpdateIdentity(identity Identity) error {
+	return UpdateConfig(func(config *Config) error {
+		for i := range config.Identities {
+			if config.Identities[i].Alias == identity.Alias {
+				config.Identities[i] = identity
+				return nil
+			}
+		}
+		return fmt.Errorf("identity '%s' not found", identity.Alias)
+	})
 }
 
 func RemoveIdentity(alias string) error {
-	config, err := LoadConfig()
-	if err != nil {
-		return err
-	}
+	return UpdateConfig(func(config *Config) error {
+		found := false
+		identities := []Identity{}
+		for _, id := range config.Identities {
+			if id.Alias == alias {
+				found = true
+			} else {
+				identities = append(identities, id)
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("identity '%s' not found", alias)
+		}
+
+		config.Identities = identities
+		// Its repositories would otherwise pass to an identity added later under the same alias
+		repos := []BoundRepo{}
+		for _, repo := range config.BoundRepos {
+			if repo.Alias != alias {
+				repos = append(repos, repo)
+			}
+		}
+		config.BoundRepos = repos
+		return nil
+	})
+}
+
+// RenameIdentity replaces the identity with alias oldAlias by renamed and
+// moves its secret index and bound repositories over to renamed.Alias
+func RenameIdentity(oldAlias string, renamed Identity) error {
+	return UpdateConfig(func(config *Config) error {
+		index := -1
+		for i, id := range config.Identities {
+			if id.Alias == renamed.Alias && renamed.Alias != oldAlias {
+				return fmt.Errorf("identity with alias '%s' already exists", renamed.Alias)
+			}
+			if id.Alias == oldAlias {
+				index = i
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("identity '%s' not found", oldAlias)
+		}
+		config.Identities[index] = renamed
+
+		if records, ok := config.Secrets[oldAlias]; ok {
+			delete(config.Secrets, oldAlias)
+			config.Secrets[renamed.Alias] = records
+		}
+		for i := range config.BoundRepos {
+			if config.BoundRepos[i].Alias == oldAlias {
+				config.BoundRepos[i].Alias = renamed.Alias
+			}
+		}
+		return nil
+	})
+}
+
+// RecordSecret adds key to alias's secret index, or bumps its timestamp
+func RecordSecret(alias, key string, updatedAt time.Time) error {
+	return UpdateConfig(func(config *Config) error {
+		if config.Secrets == nil {
+			config.Secrets = map[string][]SecretRecord{}
+		}
+		records := config.Secrets[alias]
+		for i := range records {
+			if records[i].Key == key {
+				records[i].UpdatedAt = updatedAt
+				return nil
+			}
+		}
+		config.Secrets[alias] = append(records, SecretRecord{Key: key, UpdatedAt: updatedAt})
+		return nil
+	})
+}
 
-	found := false
-	identities := []Identity{}
-	for _, id := range config.Identities {
-		if id.Alias == alias {
-			found = true
+// ForgetSecret removes key from alias's secret index
+func ForgetSecret(alias, key string) error {
+	return UpdateConfig(func(config *Config) error {
+		records := []SecretRecord{}
+		for _, record := range config.Secrets[alias] {
+			if record.Key != key {
+				records = append(records, record)
+			}
+		}
+		if len(records) == 0 {
+			delete(config.Secrets, alias)
 		} else {
-			identities = append(identities, id)
+			config.Secrets[alias] = records
 		}
-	}
+		return nil
+	})
+}
 
-	if !found {
-		return fmt.Errorf("identity '%s' not found", alias)
+// ListSecrets returns the secret index for alias
+func ListSecrets(alias string) ([]SecretRecord, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
 	}
+	return config.Secrets[alias], nil
+}
 
-	config.Identities = identities
-	return SaveConfig(config)
+// RecordBinding notes that the repository at path is bound to alias,
+// replacing any previous binding of that repository
+func RecordBinding(path, alias string, boundAt time.Time) error {
+	return UpdateConfig(func(config *Config) error {
+		repos := []BoundRepo{}
+		for _, repo := range config.BoundRepos {
+			if repo.Path != path {
+				repos = append(repos, repo)
+			}
+		}
+		config.BoundRepos = append(repos, BoundRepo{Path: path, Alias: alias, BoundAt: boundAt})
+		return nil
+	})
 }
 
+// ForgetBinding removes the repository at path from the bound repositories
+func ForgetBinding(path string) error {
+	return UpdateConfig(func(config *Config) error {
+		repos := []BoundRepo{}
+		for _, repo := range config.BoundRepos {
+			if repo.Path != path {
+				repos = append(repos, repo)
+			}
+		}
+		config.BoundRepos = repos
+		return nil
+	})
+}
+
+// ReposBoundTo returns the recorded repositories bound to alias
+func (c *Config) ReposBoundTo(alias string) []BoundRepo {
+	var repos []BoundRepo
+	for _, repo := range c.BoundRepos {
+		if repo.Alias == alias {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestConfigDir(t *testing.T) {
@@ -120,6 +125,11 @@ func TestRemoveIdentity(t *testing.T) {
 	}
 
 	AddIdentity(identity)
+	other := identity
+	other.Alias = "other"
+	AddIdentity(other)
+	RecordBinding("/repos/a", "temp", time.Now())
+	RecordBinding("/repos/b", "other", time.Now())
 
 	if err := RemoveIdentity("temp"); err != nil {
 		t.Fatalf("Failed to remove identity: %v", err)
@@ -129,5 +139,409 @@ func TestRemoveIdentity(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when finding removed identity")
 	}
+
+	// Its bound repositories are forgotten with it
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repos := cfg.ReposBoundTo("temp"); len(repos) != 0 {
+		t.Errorf("repositories still bound to removed identity: %v", repos)
+	}
+	if repos := cfg.ReposBoundTo("other"); len(repos) != 1 {
+		t.Errorf("other identity's repositories = %v", repos)
+	}
+}
+
+
+func TestSecretIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	if err := RecordSecret("work", "pat", first); err != nil {
+		t.Fatalf("RecordSecret: %v", err)
+	}
+	if err := RecordSecret("work", "ssh_passphrase", first); err != nil {
+		t.Fatalf("RecordSecret: %v", err)
+	}
+	if err := RecordSecret("work", "pat", second); err != nil {
+		t.Fatalf("RecordSecret: %v", err)
+	}
+
+	records, err := ListSecrets("work")
+	if err != nil {
+		t.Fatalf("ListSecrets: %v", err)
+	}
+	if len(records) != 2 || records[0].Key != "pat" || !records[0].UpdatedAt.Equal(second) {
+		t.Errorf("unexpected index after update: %+v", records)
+	}
+
+	if err := ForgetSecret("work", "pat"); err != nil {
+		t.Fatalf("ForgetSecret: %v", err)
+	}
+	if err := ForgetSecret("work", "ssh_passphrase"); err != nil {
+		t.Fatalf("ForgetSecret: %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if _, ok := cfg.Secrets["work"]; ok {
+		t.Errorf("expected empty index to be dropped, got %+v", cfg.Secrets)
+	}
+}
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	legacy := `{"identities": [
+		{"alias": "work", "name": "W", "email": "w@example.com", "github_user": "w", "auth_method": "PAT"},
+		{"alias": "home", "name": "H", "email": "h@example.com", "github_user": "h", "auth_method": ""}
+	]}`
+	path, _ := GetIdentitiesPath()
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+	if cfg.Identities[0].AuthMethod != "pat" || cfg.Identities[1].AuthMethod != "ssh" {
+		t.Errorf("auth methods not normalised: %+v", cfg.Identities)
+	}
+	if records := cfg.Secrets["work"]; len(records) != 1 || records[0].Key != "pat" {
+		t.Errorf("legacy PAT not indexed: %+v", cfg.Secrets)
+	}
+
+	backup, err := os.ReadFile(path + ".v1.bak")
+	if err != nil || string(backup) != legacy {
+		t.Errorf("pre-migration backup missing or changed: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+	if !strings.Contains(string(saved), `"version": 2`) {
+		t.Errorf("migrated config not saved: %s", saved)
+	}
+}
+
+func TestLoadCurrentConfigWritesNothing(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	path, _ := GetIdentitiesPath()
+	current := `{"version": 2, "identities": [{"alias": "work", "auth_method": "ssh"}]}`
+	if err := os.WriteFile(path, []byte(current), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Identities) != 1 || cfg.Identities[0].Alias != "work" {
+		t.Errorf("unexpected identities: %+v", cfg.Identities)
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("loading a current config wrote files: %v", entries)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != current {
+		t.Errorf("config rewritten: %s", saved)
+	}
+}
+
+func TestRefuseNewerConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	path, _ := GetIdentitiesPath()
+	os.WriteFile(path, []byte(`{"version": 99, "identities": []}`), 0600)
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "newer gitx") {
+		t.Errorf("expected a newer-version error, got %v", err)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			alias := fmt.Sprintf("id%d", i)
+			if err := AddIdentity(Identity{Alias: alias, AuthMethod: "ssh"}); err != nil {
+				t.Errorf("AddIdentity(%s): %v", alias, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Identities) != 20 {
+		t.Errorf("expected 20 identities after concurrent adds, got %d", len(cfg.Identities))
+	}
+}
+
+func TestRecoverCorruptConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	if err := AddIdentity(Identity{Alias: "work", AuthMethod: "ssh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddIdentity(Identity{Alias: "home", AuthMethod: "ssh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a write cut short
+	path, _ := GetIdentitiesPath()
+	os.WriteFile(path, []byte(`{"version": 2, "identit`), 0600)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig should recover from the backup: %v", err)
+	}
+	if len(cfg.Identities) != 1 || cfg.Identities[0].Alias != "work" {
+		t.Errorf("expected the previous config from the backup, got %+v", cfg.Identities)
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Errorf("corrupt copy not kept: %v", err)
+	}
+	if _, err := FindIdentityByAlias("work"); err != nil {
+		t.Errorf("recovered config not written back: %v", err)
+	}
+}
+
+func TestConfigDirPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigDirEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	defer func() { ConfigDirOverride = "" }()
+
+	check := func(want string) {
+		t.Helper()
+		got, err := GetConfigDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("config dir = %s, want %s", got, want)
+		}
+	}
+
+	legacy := home + "/.config/gitx"
+	check(legacy)
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	check(xdg + "/gitx")
+
+	// An existing ~/.config/gitx isn't hidden by a fresh XDG_CONFIG_HOME
+	os.MkdirAll(legacy, 0755)
+	check(legacy)
+
+	t.Setenv(ConfigDirEnv, "/tmp/gitx-env")
+	check("/tmp/gitx-env")
+
+	ConfigDirOverride = "/tmp/gitx-flag"
+	check("/tmp/gitx-flag")
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	expires := time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)
+	rotated := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	want := &Config{
+		Version: CurrentVersion,
+		Identities: []Identity{{
+			Alias:             "work",
+			Name:              "Work Person",
+			Email:             "work@example.com",
+			GitHubUser:        "work-gh",
+			SSHKeyPath:        "/keys/gitx_work",
+			AuthMethod:        "ssh",
+			SSHHostAlias:      "github.com-work",
+			SSHCertPath:       "/keys/gitx_work-cert.pub",
+			ExternalHostEntry: true,
+			IsolatedAgent:     true,
+			KeyRotations: []KeyRotation{{
+				RotatedAt: rotated, OldKeyPath: "/keys/old", OldFingerprint: "SHA256:old",
+				NewKeyPath: "/keys/gitx_work", NewFingerprint: "SHA256:new", GraceUntil: expires,
+			}},
+			PAT: &PATInfo{ExpiresAt: &expires, Scopes: []string{"repo", "workflow"}, Note: "ci"},
+		}},
+		PinnedHostKeys:       map[string][]string{"github.com": {"ssh-ed25519 AAAA"}},
+		KeyringBackend:       "file",
+		Secrets:              map[string][]SecretRecord{"work": {{Key: "pat", UpdatedAt: rotated}}},
+		PATExpiryWarningDays: 7,
+		BoundRepos:           []BoundRepo{{Path: "/src/repo", Alias: "work", BoundAt: rotated}},
+	}
+
+	for _, format := range Formats() {
+		t.Run(string(format), func(t *testing.T) {
+			tmpDir := t.TempDir()
+			originalGetConfigDir := getConfigDirFunc
+			defer func() { getConfigDirFunc = originalGetConfigDir }()
+			getConfigDirFunc = func() (string, error) {
+				return tmpDir, nil
+			}
+
+			path := tmpDir + "/" + format.FileName()
+			if err := saveConfig(path, want); err != nil {
+				t.Fatal(err)
+			}
+			if found, _ := GetIdentitiesPath(); found != path {
+				t.Fatalf("identities file = %s, want %s", found, path)
+			}
+			got, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("round trip through %s changed the config:\n got %+v\nwant %+v", format, got, want)
+			}
+		})
+	}
 }
 
+func TestYAMLKeepsComments(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	os.WriteFile(tmpDir+"/identities.yaml", []byte(`version: 2
+identities:
+  # day job
+  - alias: work
+    name: W
+    email: w@example.com # shared inbox
+    github_user: w
+    auth_method: ssh
+`), 0600)
+
+	if err := AddIdentity(Identity{Alias: "home", AuthMethod: "ssh"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(tmpDir + "/identities.yaml")
+	for _, comment := range []string{"# day job", "# shared inbox"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("comment %q lost:\n%s", comment, data)
+		}
+	}
+	if _, err := os.Stat(tmpDir + "/identities.json"); err == nil {
+		t.Error("saving a YAML config must not create identities.json")
+	}
+}
+
+func TestRenameIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalGetConfigDir := getConfigDirFunc
+	defer func() { getConfigDirFunc = originalGetConfigDir }()
+	getConfigDirFunc = func() (string, error) {
+		return tmpDir, nil
+	}
+
+	AddIdentity(Identity{Alias: "work", AuthMethod: "pat"})
+	AddIdentity(Identity{Alias: "home", AuthMethod: "ssh"})
+	RecordSecret("work", "pat", time.Now())
+	RecordBinding("/src/a", "work", time.Now())
+	RecordBinding("/src/b", "home", time.Now())
+
+	if err := RenameIdentity("work", Identity{Alias: "home", AuthMethod: "pat"}); err == nil {
+		t.Error("renaming onto an existing alias should fail")
+	}
+	if err := RenameIdentity("work", Identity{Alias: "job", AuthMethod: "pat"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _ := LoadConfig()
+	if _, err := FindIdentityByAlias("work"); err == nil {
+		t.Error("old alias still present")
+	}
+	if len(cfg.Secrets["job"]) != 1 || cfg.Secrets["work"] != nil {
+		t.Errorf("secret index not moved: %+v", cfg.Secrets)
+	}
+	if repos := cfg.ReposBoundTo("job"); len(repos) != 1 || repos[0].Path != "/src/a" {
+		t.Errorf("bound repos not moved: %+v", cfg.BoundRepos)
+	}
+	if repos := cfg.ReposBoundTo("home"); len(repos) != 1 {
+		t.Errorf("other bindings changed: %+v", cfg.BoundRepos)
+	}
+}
+
+func TestIdentityValidate(t *testing.T) {
+	valid := Identity{
+		Alias: "work", Name: "Jo", Email: "jo@example.com", GitHubUser: "jo-work", AuthMethod: "ssh", SSHHostAlias: "github.com-work",
+		Emails:    []string{"1234+jo@users.noreply.github.com"},
+		GitConfig: map[string]string{"pull.rebase": "true", "url.git@github.com:.insteadOf": "https://github.com/"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid identity rejected: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Identity)
+	}{
+		{"alias with space", func(i *Identity) { i.Alias = "my work" }},
+		{"alias with newline", func(i *Identity) { i.Alias = "work\nHost *" }},
+		{"empty name", func(i *Identity) { i.Name = " " }},
+		{"email without domain", func(i *Identity) { i.Email = "jo" }},
+		{"email with display name", func(i *Identity) { i.Email = "Jo <jo@example.com>" }},
+		{"username with underscore", func(i *Identity) { i.GitHubUser = "jo_work" }},
+		{"username ending in hyphen", func(i *Identity) { i.GitHubUser = "jo-" }},
+		{"unknown auth method", func(i *Identity) { i.AuthMethod = "gpg" }},
+		{"host alias pattern", func(i *Identity) { i.SSHHostAlias = "github.com-*" }},
+		{"missing key", func(i *Identity) { i.SSHKeyPath = "/nonexistent/gitx_work" }},
+		{"duplicate email", func(i *Identity) { i.Emails = []string{"JO@example.com"} }},
+		{"git config key without section", func(i *Identity) { i.GitConfig = map[string]string{"rebase": "true"} }},
+		{"reserved git config key", func(i *Identity) { i.GitConfig = map[string]string{"User.Email": "x@example.com"} }},
+		{"reserved git config prefix", func(i *Identity) { i.GitConfig = map[string]string{"remote.origin.url": "x"} }},
+	}
+	for _, tt := range tests {
+		identity := valid
+		tt.modify(&identity)
+		if err := identity.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
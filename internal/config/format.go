@@ -0,0 +1,297 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the file format of the identities file
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// identitiesFiles lists the names the identities file is looked up by
+var identitiesFiles = []string{IdentitiesFile, "identities.yaml", "identities.yml", "identities.toml"}
+
+// Formats returns the supported config formats
+func Formats() []Format {
+	return []Format{FormatJSON, FormatYAML, FormatTOML}
+}
+
+// ParseFormat parses a format name as given on the command line
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q (valid: json, yaml, toml)", name)
+}
+
+// FormatOf returns the format of an identities file from its extension
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// FileName returns the identities file name used for format
+func (f Format) FileName() string {
+	return "identities." + string(f)
+}
+
+// findIdentitiesFile returns the identities file present in configDir, or the
+// JSON one when there is none yet. Several candidates at once are refused, as
+// gitx couldn't know which one is current.
+func findIdentitiesFile(configDir string) (string, error) {
+	var found []string
+	for _, name := range identitiesFiles {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return filepath.Join(configDir, IdentitiesFile), nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("found several identities files (%s); keep only one", strings.Join(found, ", "))
+}
+
+// toJSON converts the contents of a config file in format to JSON, the form
+// migrations and decoding work on
+func toJSON(format Format, data []byte) ([]byte, error) {
+	var doc interface{}
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("empty document")
+		}
+	case FormatTOML:
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(data), &table); err != nil {
+			return nil, err
+		}
+		doc = table
+	default:
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("invalid JSON")
+		}
+		return data, nil
+	}
+	return json.Marshal(doc)
+}
+
+// encodeConfig renders config in format. For YAML, comments in previous (the
+// file being replaced, may be nil) are carried over to the matching entries.
+func encodeConfig(config *Config, format Format, previous []byte) ([]byte, error) {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	switch format {
+	case FormatYAML:
+		node, err := jsonToYAML(json.NewDecoder(bytes.NewReader(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+		var old yaml.Node
+		if previous != nil && yaml.Unmarshal(previous, &old) == nil && len(old.Content) > 0 {
+			doc.HeadComment, doc.FootComment = old.HeadComment, old.FootComment
+			carryComments(old.Content[0], node)
+		}
+		var out bytes.Buffer
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return out.Bytes(), nil
+
+	case FormatTOML:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc interface{}
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		var out bytes.Buffer
+		enc := toml.NewEncoder(&out)
+		enc.Indent = ""
+		if err := enc.Encode(tomlValue(doc)); err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+	return data, nil
+}
+
+// jsonToYAML builds a YAML node from the next JSON value of dec, keeping the
+// key order of the struct it was marshalled from
+func jsonToYAML(dec *json.Decoder) (*yaml.Node, error) {
+	dec.UseNumber()
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		if value == '{' {
+			node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := jsonToYAML(dec)
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)}, child)
+			}
+			_, err := dec.Token() // closing brace
+			return node, err
+		}
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for dec.More() {
+			child, err := jsonToYAML(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		_, err := dec.Token() // closing bracket
+		return node, err
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(value)}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	return nil, fmt.Errorf("unexpected JSON token %v", token)
+}
+
+// carryComments copies comments from old onto the corresponding nodes of
+// updated. List items are matched by their alias, path or key field when they
+// have one, so comments follow an identity when others are added or removed.
+func carryComments(old, updated *yaml.Node) {
+	if old == nil || updated == nil || old.Kind != updated.Kind {
+		return
+	}
+	if updated.HeadComment == "" {
+		updated.HeadComment = old.HeadComment
+	}
+	if updated.LineComment == "" {
+		updated.LineComment = old.LineComment
+	}
+	if updated.FootComment == "" {
+		updated.FootComment = old.FootComment
+	}
+
+	switch updated.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			for j := 0; j+1 < len(old.Content); j += 2 {
+				if old.Content[j].Value == updated.Content[i].Value {
+					carryComments(old.Content[j], updated.Content[i])
+					carryComments(old.Content[j+1], updated.Content[i+1])
+					break
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range updated.Content {
+			if id := itemID(item); id != "" {
+				for _, candidate := range old.Content {
+					if itemID(candidate) == id {
+						carryComments(candidate, item)
+						break
+					}
+				}
+			} else if i < len(old.Content) {
+				carryComments(old.Content[i], item)
+			}
+		}
+	}
+}
+
+// itemID identifies a list entry by its alias, path or key field
+func itemID(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for _, field := range []string{"alias", "path", "key"} {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == field {
+				return field + "=" + node.Content[i+1].Value
+			}
+		}
+	}
+	return ""
+}
+
+// tomlValue prepares a decoded JSON document for the TOML encoder: TOML has
+// no null, and whole numbers should stay integers
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, item := range v {
+			if item != nil {
+				out[key] = tomlValue(item)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				out = append(out, tomlValue(item))
+			}
+		}
+		return out
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// lockConfig takes the advisory lock that serialises read-modify-write cycles
+// of the identities file in configDir between gitx processes. The returned
+// func releases it.
+func lockConfig(configDir string) (func(), error) {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(configDir, "identities.lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock config: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// writeConfigFile replaces path with data without ever leaving a partial file
+// behind: the data is written to a temporary file in the same directory,
+// synced, and renamed over path. The previous contents, if they parse, are
+// kept as path.bak first.
+func writeConfigFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if previous, err := os.ReadFile(path); err == nil && parses(path, previous) {
+		if err := os.WriteFile(path+".bak", previous, 0600); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	// Persist the rename itself; not supported everywhere, so best effort
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// readConfigFile returns the contents of path. When they don't parse (e.g.
+// truncated by a crash) the rolling backup is returned instead, and
+// recovered reports that the caller should write it back.
+func readConfigFile(path string) (data []byte, recovered bool, err error) {
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read config: %w", err)
+	}
+	if parses(path, data) {
+		return data, false, nil
+	}
+
+	backup, bakErr := os.ReadFile(path + ".bak")
+	if bakErr != nil || !parses(path, backup) {
+		return nil, false, fmt.Errorf("failed to parse config: %s is corrupt and no usable backup exists at %s.bak", path, path)
+	}
+	// Keep the damaged file around for inspection
+	corrupt := path + ".corrupt"
+	if err := os.WriteFile(corrupt, data, 0600); err != nil {
+		return nil, false, fmt.Errorf("failed to preserve corrupt config: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "warning: %s was corrupt; restored from %s.bak (damaged copy kept at %s)\n", path, path, corrupt)
+	return backup, true, nil
+}
+
+// parses reports whether data is a well-formed document in the format of path
+func parses(path string, data []byte) bool {
+	_, err := toJSON(FormatOf(path), data)
+	return err == nil
+}
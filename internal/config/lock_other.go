@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package config
+
+import "os"
+
+// Platforms without advisory locks only get the atomic replace in writeConfigFile
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
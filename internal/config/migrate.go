@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// CurrentVersion is the config schema written by this version of gitx.
+// Files without a version field are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a raw config document from one version to the next
+type migration struct {
+	from        int
+	description string
+	apply       func(raw map[string]interface{}) error
+}
+
+// migrations run in order on LoadConfig; each takes a file at version `from`
+// to from+1. Append new steps here and bump CurrentVersion.
+var migrations = []migration{
+	{
+		from:        1,
+		description: "normalise auth methods and index legacy PATs",
+		apply:       migrateV1ToV2,
+	},
+}
+
+// migrateV1ToV2 lower-cases auth_method (empty meant ssh) and records the PAT
+// of each PAT identity in the secret index, which didn't exist in version 1
+func migrateV1ToV2(raw map[string]interface{}) error {
+	identities, _ := raw["identities"].([]interface{})
+	secrets, _ := raw["secrets"].(map[string]interface{})
+	if secrets == nil {
+		secrets = map[string]interface{}{}
+	}
+
+	for _, item := range identities {
+		identity, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("identities: unexpected entry %v", item)
+		}
+		method, _ := identity["auth_method"].(string)
+		method = strings.ToLower(strings.TrimSpace(method))
+		if method == "" {
+			method = "ssh"
+		}
+		identity["auth_method"] = method
+
+		alias, _ := identity["alias"].(string)
+		if method == "pat" && alias != "" && secrets[alias] == nil {
+			// The time it was stored is unknown
+			secrets[alias] = []interface{}{
+				map[string]interface{}{"key": "pat", "updated_at": time.Time{}},
+			}
+		}
+	}
+
+	if len(secrets) > 0 {
+		raw["secrets"] = secrets
+	}
+	return nil
+}
+
+// configVersion reads the version field of a raw config document
+func configVersion(raw map[string]interface{}) (int, error) {
+	value, ok := raw["version"]
+	if !ok {
+		return 1, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number < 1 || number != float64(int(number)) {
+		return 0, fmt.Errorf("invalid config version %v", value)
+	}
+	return int(number), nil
+}
+
+// migrateConfig upgrades data (the JSON form of original, the config file at
+// path) to CurrentVersion. It returns the migrated JSON document, or nil when
+// data is already current.
+func migrateConfig(path string, original, data []byte) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	version, err := configVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("%s was written by a newer gitx (config version %d, this gitx supports up to %d); please upgrade gitx", path, version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return nil, nil
+	}
+
+	// Keep the file as it was before any step touched it
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := os.WriteFile(backupPath, original, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config before migration: %w", err)
+	}
+
+	for _, step := range migrations {
+		if step.from < version {
+			continue
+		}
+		if step.from != version {
+			return nil, fmt.Errorf("no migration from config version %d", version)
+		}
+		if err := step.apply(raw); err != nil {
+			return nil, fmt.Errorf("config migration %d -> %d (%s) failed: %w", step.from, step.from+1, step.description, err)
+		}
+		version++
+		raw["version"] = version
+	}
+	if version != CurrentVersion {
+		return nil, fmt.Errorf("no migration from config version %d", version)
+	}
+
+	return json.Marshal(raw)
+}
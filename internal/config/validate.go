@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// aliasPattern limits aliases and SSH host aliases to what is safe in file
+	// names and Host lines
+	aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	// gitConfigKeyPattern matches "section.name" and "section.subsection.name"
+	gitConfigKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*\.(?:.*\.)?[A-Za-z][A-Za-z0-9-]*$`)
+	// githubUserPattern follows GitHub's rules: up to 39 letters, digits and
+	// inner hyphens
+	githubUserPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,37}[A-Za-z0-9])?$`)
+)
+
+// ErrKeyNotFound is reported when an identity's SSH key file doesn't exist
+var ErrKeyNotFound = errors.New("SSH key not found")
+
+// reservedGitConfig are keys, or key prefixes, that bind manages itself
+var reservedGitConfig = []string{"user.name", "user.email", "credential.helper", "remote.", "gitx.", "gitx-override."}
+
+// ValidateAlias checks that alias can be used as an identity alias
+func ValidateAlias(alias string) error {
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("invalid alias %q: use letters, digits, '.', '_' and '-'", alias)
+	}
+	return nil
+}
+
+// Validate checks the identity's fields, and that its SSH key exists. All
+// problems found are returned together.
+func (i *Identity) Validate() error {
+	return errors.Join(i.Problems()...)
+}
+
+// Problems returns everything Validate finds wrong with the identity
+func (i *Identity) Problems() []error {
+	var problems []error
+	if err := ValidateAlias(i.Alias); err != nil {
+		problems = append(problems, err)
+	}
+	if strings.TrimSpace(i.Name) == "" {
+		problems = append(problems, fmt.Errorf("name cannot be empty"))
+	} else if strings.IndexFunc(i.Name, unicode.IsControl) >= 0 {
+		problems = append(problems, fmt.Errorf("name %q contains control characters", i.Name))
+	}
+	seen := map[string]bool{}
+	for _, email := range i.AllowedEmails() {
+		if !validEmail(email) {
+			problems = append(problems, fmt.Errorf("invalid email %q", email))
+		} else if seen[strings.ToLower(email)] {
+			problems = append(problems, fmt.Errorf("email %s is listed more than once", email))
+		}
+		seen[strings.ToLower(email)] = true
+	}
+	if !githubUserPattern.MatchString(i.GitHubUser) {
+		problems = append(problems, fmt.Errorf("invalid GitHub username %q", i.GitHubUser))
+	}
+	if i.AuthMethod != "ssh" && i.AuthMethod != "pat" {
+		problems = append(problems, fmt.Errorf("auth method must be ssh or pat, not %q", i.AuthMethod))
+	}
+	if i.SSHHostAlias != "" && !aliasPattern.MatchString(i.SSHHostAlias) {
+		problems = append(problems, fmt.Errorf("invalid SSH host alias %q", i.SSHHostAlias))
+	}
+	for key, value := range i.GitConfig {
+		if err := validateGitConfig(key, value); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if i.SSHKeyPath != "" {
+		if _, err := os.Stat(i.SSHKeyPath); err != nil {
+			problems = append(problems, fmt.Errorf("%w: %s", ErrKeyNotFound, i.SSHKeyPath))
+		}
+	}
+	return problems
+}
+
+// validateGitConfig checks an entry of an identity's GitConfig
+func validateGitConfig(key, value string) error {
+	if !gitConfigKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid git config key %q", key)
+	}
+	for _, reserved := range reservedGitConfig {
+		if strings.EqualFold(key, reserved) || (strings.HasSuffix(reserved, ".") && strings.HasPrefix(strings.ToLower(key), reserved)) {
+			return fmt.Errorf("git config key %s is set by gitx itself", key)
+		}
+	}
+	if strings.ContainsAny(value, "\n\x00") {
+		return fmt.Errorf("git config value of %s contains a newline", key)
+	}
+	return nil
+}
+
+// validEmail reports whether email is a bare address, without a display name
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && address.Name == ""
+}
+
+// Lint checks every identity in the config, and that aliases and SSH host
+// aliases are unique and bound repositories refer to known identities
+func (c *Config) Lint() []error {
+	var problems []error
+	aliases := map[string]bool{}
+	hosts := map[string]string{}
+	for i := range c.Identities {
+		identity := &c.Identities[i]
+		for _, problem := range identity.Problems() {
+			problems = append(problems, fmt.Errorf("identity '%s': %w", identity.Alias, problem))
+		}
+		if aliases[identity.Alias] {
+			problems = append(problems, fmt.Errorf("identity '%s': alias is used more than once", identity.Alias))
+		}
+		aliases[identity.Alias] = true
+		if identity.SSHHostAlias == "" {
+			continue
+		}
+		if other, ok := hosts[identity.SSHHostAlias]; ok && other != identity.Alias {
+			problems = append(problems, fmt.Errorf("identity '%s': SSH host alias %s is also used by '%s'", identity.Alias, identity.SSHHostAlias, other))
+		}
+		hosts[identity.SSHHostAlias] = identity.Alias
+	}
+	for _, repo := range c.BoundRepos {
+		if !aliases[repo.Alias] {
+			problems = append(problems, fmt.Errorf("bound repository %s: unknown identity '%s'", repo.Path, repo.Alias))
+		}
+	}
+	return problems
+}
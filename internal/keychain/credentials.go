@@ -0,0 +1,286 @@
+package keychain
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CredentialEntry is one line of a git-credential-store file
+type CredentialEntry struct {
+	File     string
+	Line     string // raw line, including the password
+	Protocol string
+	Host     string // host[:port]
+	Path     string
+	Username string // URL-decoded
+	Password string // URL-decoded
+}
+
+// String renders the entry without its password
+func (e CredentialEntry) String() string {
+	u := url.URL{Scheme: e.Protocol, Host: e.Host, Path: e.Path}
+	if e.Username != "" {
+		u.User = url.User(e.Username)
+	}
+	return u.String()
+}
+
+// Matches reports whether the entry is for username at host. An empty
+// username matches any entry for the host.
+func (e CredentialEntry) Matches(username, host string) bool {
+	if !strings.EqualFold(e.Host, host) {
+		return false
+	}
+	return username == "" || e.Username == username
+}
+
+// CredentialCleanup reports what RemoveGitCredentials did
+type CredentialCleanup struct {
+	// Helpers is the credential.helper chain asked to erase the credentials
+	Helpers []string
+	// Removed lists the store-file entries that were deleted
+	Removed []CredentialEntry
+}
+
+// ParseCredentialStore parses the contents of a git-credential-store file.
+// Lines that aren't URLs with a host are skipped.
+func ParseCredentialStore(file, content string) []CredentialEntry {
+	var entries []CredentialEntry
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		entry := CredentialEntry{
+			File:     file,
+			Line:     line,
+			Protocol: u.Scheme,
+			Host:     u.Host,
+			Path:     u.Path,
+		}
+		if u.User != nil {
+			entry.Username = u.User.Username()
+			entry.Password, _ = u.User.Password()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// CredentialHelpers returns the credential.helper chain in effect for the
+// current directory, in the order git consults it
+func CredentialHelpers() ([]string, error) {
+	output, err := exec.Command("git", "config", "--get-all", "credential.helper").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil, nil // not set
+		}
+		return nil, fmt.Errorf("failed to read credential.helper: %w", err)
+	}
+	var helpers []string
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			// An empty value resets the list
+			helpers = nil
+			continue
+		}
+		helpers = append(helpers, line)
+	}
+	return helpers, nil
+}
+
+// credentialStoreFiles returns the files git-credential-store reads: the
+// defaults plus any --file given to a store helper in the chain
+func credentialStoreFiles(helpers []string) []string {
+	var files []string
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".git-credentials"))
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		if xdg == "" {
+			xdg = filepath.Join(home, ".config")
+		}
+		files = append(files, filepath.Join(xdg, "git", "credentials"))
+	}
+
+	for _, helper := range helpers {
+		fields := strings.Fields(helper)
+		if len(fields) == 0 || fields[0] != "store" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			path := ""
+			if strings.HasPrefix(field, "--file=") {
+				path = strings.TrimPrefix(field, "--file=")
+			} else if field == "--file" && i+2 < len(fields) {
+				path = fields[i+2]
+			}
+			if path == "" {
+				continue
+			}
+			if strings.HasPrefix(path, "~/") {
+				if home, err := os.UserHomeDir(); err == nil {
+					path = filepath.Join(home, path[2:])
+				}
+			}
+			files = append(files, path)
+		}
+	}
+
+	seen := map[string]bool{}
+	unique := files[:0]
+	for _, file := range files {
+		if !seen[file] {
+			seen[file] = true
+			unique = append(unique, file)
+		}
+	}
+	return unique
+}
+
+// RemoveGitCredentials erases githubUser's credentials for each host from every
+// helper in the credential.helper chain with 'git credential reject', then
+// removes any matching entries left in credential-store files (for example
+// ones a helper outside the chain wrote, or with URL-encoded usernames)
+func RemoveGitCredentials(githubUser string, hosts []string) (*CredentialCleanup, error) {
+	helpers, err := CredentialHelpers()
+	if err != nil {
+		return nil, err
+	}
+	cleanup := &CredentialCleanup{Helpers: helpers}
+
+	// Snapshot the store files so entries erased by the store helper itself are reported too
+	files := credentialStoreFiles(helpers)
+	before := map[string][]CredentialEntry{}
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			before[file] = ParseCredentialStore(file, string(data))
+		}
+	}
+
+	var failures []string
+	if len(helpers) > 0 {
+		for _, host := range hosts {
+			cmd := exec.Command("git", "credential", "reject")
+			cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+			cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\nusername=%s\n\n", host, githubUser))
+			if output, err := cmd.CombinedOutput(); err != nil {
+				failures = append(failures, fmt.Sprintf("git credential reject for %s: %s", host, strings.TrimSpace(string(output))))
+			}
+		}
+	}
+
+	for _, file := range files {
+		if err := removeStoreEntries(file, githubUser, hosts); err != nil {
+			failures = append(failures, err.Error())
+		}
+
+		remaining := map[string]bool{}
+		if data, err := os.ReadFile(file); err == nil {
+			for _, entry := range ParseCredentialStore(file, string(data)) {
+				remaining[entry.Line] = true
+			}
+		}
+		for _, entry := range before[file] {
+			if !remaining[entry.Line] {
+				cleanup.Removed = append(cleanup.Removed, entry)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return cleanup, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return cleanup, nil
+}
+
+// removeStoreEntries drops the https entries of a store file that match
+// githubUser at any of hosts
+func removeStoreEntries(file, githubUser string, hosts []string) error {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	drop := map[string]bool{}
+	for _, entry := range ParseCredentialStore(file, string(data)) {
+		for _, host := range hosts {
+			if entry.Protocol == "https" && entry.Matches(githubUser, host) {
+				drop[entry.Line] = true
+			}
+		}
+	}
+	if len(drop) == 0 {
+		return nil
+	}
+
+	var kept []string
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !drop[trimmed] {
+			kept = append(kept, line)
+		}
+	}
+	content := strings.Join(kept, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return nil
+}
+
+// RemoveStoreLines rewrites a credential-store file without the given raw
+// lines, after copying the original to <file>.gitx.backup.<timestamp>.
+// It returns the backup path.
+func RemoveStoreLines(file string, lines []string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	backupPath := fmt.Sprintf("%s.gitx.backup.%s", file, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	drop := map[string]bool{}
+	for _, line := range lines {
+		drop[line] = true
+	}
+	var kept []string
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !drop[trimmed] {
+			kept = append(kept, line)
+		}
+	}
+	content := strings.Join(kept, "\n")
+	if content != "" {
+		content += "\n"
+	}
+
+	tempPath := file + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(content), 0600); err != nil {
+		return backupPath, fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	if err := os.Rename(tempPath, file); err != nil {
+		os.Remove(tempPath)
+		return backupPath, fmt.Errorf("failed to write %s: %w", file, err)
+	}
+	return backupPath, nil
+}
@@ -1,115 +1,220 @@
 package keychain
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/99designs/keyring"
+	"github.com/csawai/git-identity-switcher/internal/config"
 )
 
 const ServiceName = "gitx"
 
-func getKeyring() (keyring.Keyring, error) {
-	return keyring.Open(keyring.Config{
-		ServiceName: ServiceName,
-	})
+// PasswordEnv holds the passphrase for the encrypted file backend; without it
+// gitx prompts on the terminal
+const PasswordEnv = "GITX_KEYRING_PASSWORD"
+
+var (
+	// Backend is the keyring backend to use; empty tries every available
+	// backend in turn and ends with the encrypted file backend
+	Backend string
+	// FileDir is where the encrypted file backend keeps its items
+	FileDir string
+	// Interactive allows prompting on the terminal for the file backend
+	// passphrase. It is off when gitx runs as a git credential helper.
+	Interactive = true
+
+	activeBackend string
+)
+
+// Backends lists the backend names accepted by Backend
+func Backends() []string {
+	return []string{
+		string(keyring.SecretServiceBackend),
+		string(keyring.KWalletBackend),
+		string(keyring.PassBackend),
+		string(keyring.KeyCtlBackend),
+		string(keyring.KeychainBackend),
+		string(keyring.WinCredBackend),
+		string(keyring.FileBackend),
+	}
 }
 
-func StoreSecret(identityAlias, key, value string) error {
-	ring, err := getKeyring()
-	if err != nil {
-		return fmt.Errorf("failed to open keyring: %w", err)
+// AvailableBackends lists the backends usable on this system, in the order they are tried
+func AvailableBackends() []string {
+	var names []string
+	hasFile := false
+	for _, backend := range keyring.AvailableBackends() {
+		names = append(names, string(backend))
+		hasFile = hasFile || backend == keyring.FileBackend
 	}
-	secretKey := fmt.Sprintf("%s:%s", identityAlias, key)
-	return ring.Set(keyring.Item{
-		Key:  secretKey,
-		Data: []byte(value),
-	})
+	if !hasFile {
+		names = append(names, string(keyring.FileBackend))
+	}
+	return names
 }
 
-func GetSecret(identityAlias, key string) (string, error) {
-	ring, err := getKeyring()
-	if err != nil {
-		return "", fmt.Errorf("failed to open keyring: %w", err)
+// ValidBackend reports whether name is a backend gitx knows about
+func ValidBackend(name string) bool {
+	for _, backend := range Backends() {
+		if backend == name {
+			return true
+		}
 	}
-	secretKey := fmt.Sprintf("%s:%s", identityAlias, key)
-	item, err := ring.Get(secretKey)
-	if err != nil {
+	return false
+}
+
+// ActiveBackend opens the keyring and returns the name of the backend in use
+func ActiveBackend() (string, error) {
+	if _, err := getKeyring(); err != nil {
 		return "", err
 	}
-	return string(item.Data), nil
+	return activeBackend, nil
 }
 
-func DeleteSecret(identityAlias, key string) error {
-	ring, err := getKeyring()
-	if err != nil {
-		return fmt.Errorf("failed to open keyring: %w", err)
+func filePassword(prompt string) (string, error) {
+	if password := os.Getenv(PasswordEnv); password != "" {
+		return password, nil
 	}
-	secretKey := fmt.Sprintf("%s:%s", identityAlias, key)
-	return ring.Remove(secretKey)
+	if !Interactive {
+		return "", fmt.Errorf("%s is not set", PasswordEnv)
+	}
+	return keyring.TerminalPrompt(prompt)
 }
 
-func DeleteAllSecrets(identityAlias string) error {
-	// Note: keyring doesn't support listing easily, so we delete known keys
-	keys := []string{"pat", "ssh_passphrase"}
-	for _, key := range keys {
-		DeleteSecret(identityAlias, key) // Ignore errors
+func getKeyring() (keyring.Keyring, error) {
+	candidates := AvailableBackends()
+	if Backend != "" {
+		if !ValidBackend(Backend) {
+			return nil, fmt.Errorf("unknown keyring backend %q (valid: %s)", Backend, strings.Join(Backends(), ", "))
+		}
+		candidates = []string{Backend}
 	}
-	return nil
-}
 
-// RemoveGitCredentials removes credentials from git's credential helper
-// This handles both osxkeychain (macOS) and credential store (Linux)
-func RemoveGitCredentials(githubUser string) error {
-	// Try osxkeychain first (macOS)
-	cmd := exec.Command("git", "credential-osxkeychain", "erase")
-	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=github.com\nusername=%s\n\n", githubUser))
-	if err := cmd.Run(); err == nil {
-		return nil // Success
+	// Open backends one at a time so we know which one succeeded
+	var lastErr error
+	for _, name := range candidates {
+		ring, err := keyring.Open(keyring.Config{
+			AllowedBackends:  []keyring.BackendType{keyring.BackendType(name)},
+			ServiceName:      ServiceName,
+			FileDir:          FileDir,
+			FilePasswordFunc: filePassword,
+			KeyCtlScope:      "user",
+			PassPrefix:       ServiceName,
+		})
+		if err == nil {
+			activeBackend = name
+			return ring, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", name, err)
 	}
+	return nil, lastErr
+}
 
-	// Try credential store (Linux/Windows)
-	homeDir, err := os.UserHomeDir()
+// OSStore keeps secrets in the system keyring chosen by Backend and records
+// them in the secret index of the gitx config
+type OSStore struct {
+	open func() (keyring.Keyring, error)
+	now  func() time.Time
+}
+
+// NewOSStore returns a SecretStore backed by the system keyring
+func NewOSStore() *OSStore {
+	return &OSStore{open: getKeyring, now: time.Now}
+}
+
+func (s *OSStore) ring() (keyring.Keyring, error) {
+	ring, err := s.open()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+		return nil, fmt.Errorf("failed to open keyring: %w", err)
 	}
+	return ring, nil
+}
 
-	credStorePath := filepath.Join(homeDir, ".git-credentials")
-	if _, err := os.Stat(credStorePath); os.IsNotExist(err) {
-		return nil // File doesn't exist, nothing to remove
+// Store saves value in the keyring and records key in the identity's secret index
+func (s *OSStore) Store(identityAlias, key, value string) error {
+	ring, err := s.ring()
+	if err != nil {
+		return err
 	}
+	if err := ring.Set(keyring.Item{
+		Key:  itemKey(identityAlias, key),
+		Data: []byte(value),
+	}); err != nil {
+		return err
+	}
+	if err := config.RecordSecret(identityAlias, key, s.now()); err != nil {
+		return fmt.Errorf("secret stored but the index could not be updated: %w", err)
+	}
+	return nil
+}
 
-	data, err := os.ReadFile(credStorePath)
+func (s *OSStore) Get(identityAlias, key string) (string, error) {
+	ring, err := s.ring()
 	if err != nil {
-		return fmt.Errorf("failed to read credential store: %w", err)
+		return "", err
 	}
-
-	lines := strings.Split(string(data), "\n")
-	var filteredLines []string
-	pattern := fmt.Sprintf("https://%s@github.com", githubUser)
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.Contains(line, pattern) {
-			filteredLines = append(filteredLines, line)
+	item, err := ring.Get(itemKey(identityAlias, key))
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrSecretNotFound
 		}
+		return "", err
 	}
+	return string(item.Data), nil
+}
 
-	// Also remove generic github.com entries if they match
-	// (Some users might have stored without username)
-	newContent := strings.Join(filteredLines, "\n")
-	if newContent != "" && !strings.HasSuffix(newContent, "\n") {
-		newContent += "\n"
+// Delete removes a secret from the keyring and the index. A secret that is
+// already gone from the keyring only drops out of the index.
+func (s *OSStore) Delete(identityAlias, key string) error {
+	ring, err := s.ring()
+	if err != nil {
+		return err
+	}
+	if err := ring.Remove(itemKey(identityAlias, key)); err != nil && !isNotFound(err) {
+		return err
 	}
+	return config.ForgetSecret(identityAlias, key)
+}
+
+func (s *OSStore) DeleteAll(identityAlias string) error {
+	return deleteAll(s, identityAlias)
+}
 
-	// Write back
-	if err := os.WriteFile(credStorePath, []byte(newContent), 0600); err != nil {
-		return fmt.Errorf("failed to write credential store: %w", err)
+func (s *OSStore) List(identityAlias string) ([]config.SecretRecord, error) {
+	return config.ListSecrets(identityAlias)
+}
+
+// Stored lists the keyring items for an identity, whether indexed or not
+func (s *OSStore) Stored(identityAlias string) (map[string]bool, error) {
+	ring, err := s.ring()
+	if err != nil {
+		return nil, err
 	}
+	all, err := ring.Keys()
+	if err != nil {
+		return nil, err
+	}
+	prefix := itemKey(identityAlias, "")
+	keys := map[string]bool{}
+	for _, key := range all {
+		if strings.HasPrefix(key, prefix) {
+			keys[strings.TrimPrefix(key, prefix)] = true
+		}
+	}
+	return keys, nil
+}
 
-	return nil
+func itemKey(identityAlias, key string) string {
+	return fmt.Sprintf("%s:%s", identityAlias, key)
 }
 
+// isNotFound reports whether err means the item isn't in the keyring. The file
+// backend's Remove returns the os error rather than keyring.ErrKeyNotFound.
+func isNotFound(err error) bool {
+	return errors.Is(err, keyring.ErrKeyNotFound) || errors.Is(err, fs.ErrNotExist)
+}
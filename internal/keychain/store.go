@@ -0,0 +1,137 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+// ErrSecretNotFound is returned by Get when the identity has no such secret
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore holds per-identity secrets such as PATs. Storing a key again
+// replaces its value and bumps its UpdatedAt.
+type SecretStore interface {
+	Store(identityAlias, key, value string) error
+	Get(identityAlias, key string) (string, error)
+	Delete(identityAlias, key string) error
+	// DeleteAll removes every secret of the identity and reports the ones that failed
+	DeleteAll(identityAlias string) error
+	// List returns the index of stored secrets, without values
+	List(identityAlias string) ([]config.SecretRecord, error)
+	// Stored returns the keys actually present in the backing store
+	Stored(identityAlias string) (map[string]bool, error)
+}
+
+// legacyKeys are the secrets gitx stored before it kept an index
+var legacyKeys = []string{"pat", "ssh_passphrase"}
+
+// deleteAll removes the indexed secrets plus the legacy keys, which may
+// predate the index
+func deleteAll(store SecretStore, identityAlias string) error {
+	records, err := store.List(identityAlias)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, record := range records {
+		keys = append(keys, record.Key)
+		seen[record.Key] = true
+	}
+	for _, key := range legacyKeys {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	var failed []string
+	for _, key := range keys {
+		if err := store.Delete(identityAlias, key); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", key, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete secrets: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// MemoryStore keeps secrets in memory. It backs dry runs and tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]memorySecret
+	// Now stamps stored secrets; defaults to time.Now
+	Now func() time.Time
+}
+
+type memorySecret struct {
+	value     string
+	updatedAt time.Time
+}
+
+// NewMemoryStore returns an empty in-memory SecretStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: map[string]map[string]memorySecret{}, Now: time.Now}
+}
+
+func (m *MemoryStore) Store(identityAlias, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.secrets[identityAlias] == nil {
+		m.secrets[identityAlias] = map[string]memorySecret{}
+	}
+	m.secrets[identityAlias][key] = memorySecret{value: value, updatedAt: m.Now()}
+	return nil
+}
+
+func (m *MemoryStore) Get(identityAlias, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.secrets[identityAlias][key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return secret.value, nil
+}
+
+func (m *MemoryStore) Delete(identityAlias, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets[identityAlias], key)
+	if len(m.secrets[identityAlias]) == 0 {
+		delete(m.secrets, identityAlias)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteAll(identityAlias string) error {
+	return deleteAll(m, identityAlias)
+}
+
+func (m *MemoryStore) List(identityAlias string) ([]config.SecretRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var records []config.SecretRecord
+	for key, secret := range m.secrets[identityAlias] {
+		records = append(records, config.SecretRecord{Key: key, UpdatedAt: secret.updatedAt})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return records, nil
+}
+
+func (m *MemoryStore) Stored(identityAlias string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := map[string]bool{}
+	for key := range m.secrets[identityAlias] {
+		keys[key] = true
+	}
+	return keys, nil
+}
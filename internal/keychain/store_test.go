@@ -0,0 +1,150 @@
+package keychain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/csawai/git-identity-switcher/internal/config"
+)
+
+// fakeClock hands out increasing timestamps so rotations are observable
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time {
+	c.t = c.t.Add(time.Minute)
+	return c.t
+}
+
+func newClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	store.Now = newClock().now
+	testSecretStore(t, store)
+}
+
+func TestOSStore(t *testing.T) {
+	// The secret index lives in the gitx config, which $GITX_CONFIG_DIR keeps
+	// away from the real one
+	t.Setenv(config.ConfigDirEnv, t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	ring := keyring.NewArrayKeyring(nil)
+	store := &OSStore{
+		open: func() (keyring.Keyring, error) { return ring, nil },
+		now:  newClock().now,
+	}
+	testSecretStore(t, store)
+}
+
+func TestOSStoreFileBackend(t *testing.T) {
+	t.Setenv(config.ConfigDirEnv, t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PasswordEnv, "correct horse")
+	oldBackend, oldDir := Backend, FileDir
+	defer func() { Backend, FileDir = oldBackend, oldDir }()
+	Backend, FileDir = "file", t.TempDir()
+
+	store := NewOSStore()
+	if err := store.Store("work", "pat", "ghp_file"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if got, err := store.Get("work", "pat"); err != nil || got != "ghp_file" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+	if active, err := ActiveBackend(); err != nil || active != "file" {
+		t.Errorf("ActiveBackend = %q, %v", active, err)
+	}
+
+	t.Setenv(PasswordEnv, "wrong")
+	if _, err := NewOSStore().Get("work", "pat"); err == nil {
+		t.Error("expected the wrong passphrase to fail")
+	}
+}
+
+func testSecretStore(t *testing.T, store SecretStore) {
+	t.Helper()
+
+	if _, err := store.Get("work", "pat"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Get on empty store: got %v, want ErrSecretNotFound", err)
+	}
+
+	if err := store.Store("work", "pat", "ghp_old"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store("work", "ssh_passphrase", "hunter2"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := store.Store("personal", "pat", "ghp_personal"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	before := recordTime(t, store, "work", "pat")
+
+	// Rotation replaces the value and bumps the timestamp without duplicating the entry
+	if err := store.Store("work", "pat", "ghp_new"); err != nil {
+		t.Fatalf("Store (rotate): %v", err)
+	}
+	if got, err := store.Get("work", "pat"); err != nil || got != "ghp_new" {
+		t.Fatalf("Get after rotation = %q, %v", got, err)
+	}
+	if after := recordTime(t, store, "work", "pat"); !after.After(before) {
+		t.Errorf("UpdatedAt not bumped by rotation: %v -> %v", before, after)
+	}
+	records, _ := store.List("work")
+	if len(records) != 2 {
+		t.Errorf("expected 2 indexed secrets for work, got %+v", records)
+	}
+
+	stored, err := store.Stored("work")
+	if err != nil {
+		t.Fatalf("Stored: %v", err)
+	}
+	if !stored["pat"] || !stored["ssh_passphrase"] || len(stored) != 2 {
+		t.Errorf("Stored = %v", stored)
+	}
+
+	if err := store.Delete("work", "ssh_passphrase"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get("work", "ssh_passphrase"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrSecretNotFound", err)
+	}
+	// Deleting a missing secret is not an error
+	if err := store.Delete("work", "ssh_passphrase"); err != nil {
+		t.Errorf("Delete of missing secret: %v", err)
+	}
+
+	if err := store.DeleteAll("work"); err != nil {
+		t.Fatalf("DeleteAll: %v", err)
+	}
+	if records, _ := store.List("work"); len(records) != 0 {
+		t.Errorf("index not cleared by DeleteAll: %+v", records)
+	}
+	if _, err := store.Get("work", "pat"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get after DeleteAll: got %v, want ErrSecretNotFound", err)
+	}
+
+	// Other identities are untouched
+	if got, err := store.Get("personal", "pat"); err != nil || got != "ghp_personal" {
+		t.Errorf("personal PAT = %q, %v", got, err)
+	}
+}
+
+func recordTime(t *testing.T, store SecretStore, alias, key string) time.Time {
+	t.Helper()
+	records, err := store.List(alias)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	for _, record := range records {
+		if record.Key == key {
+			return record.UpdatedAt
+		}
+	}
+	t.Fatalf("%s:%s not in index %+v", alias, key, records)
+	return time.Time{}
+}
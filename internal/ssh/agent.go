@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var agentPIDPattern = regexp.MustCompile(`SSH_AGENT_PID=(\d+)`)
+
+// AgentSocketPath returns the socket path of the dedicated agent for an identity
+func AgentSocketPath(runtimeDir, identityAlias string) string {
+	return filepath.Join(runtimeDir, "agents", identityAlias+".sock")
+}
+
+func agentPIDPath(socketPath string) string {
+	return strings.TrimSuffix(socketPath, ".sock") + ".pid"
+}
+
+// AgentRunning reports whether an agent is answering on socketPath
+func AgentRunning(socketPath string) bool {
+	if _, err := os.Stat(socketPath); err != nil {
+		return false
+	}
+	cmd := exec.Command("ssh-add", "-l")
+	cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+socketPath)
+	err := cmd.Run()
+	if err == nil {
+		return true
+	}
+	// Exit status 1 means the agent is up but holds no keys;
+	// 2 means ssh-add could not connect at all
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode() == 1
+	}
+	return false
+}
+
+// StartAgent starts a dedicated ssh-agent bound to socketPath and loads keyPath into it.
+// An agent that is already running on the socket is reused.
+func StartAgent(socketPath, keyPath string) error {
+	if !AgentRunning(socketPath) {
+		if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+			return fmt.Errorf("failed to create agent directory: %w", err)
+		}
+		// Remove a stale socket left behind by an agent that died
+		os.Remove(socketPath)
+
+		output, err := exec.Command("ssh-agent", "-s", "-a", socketPath).Output()
+		if err != nil {
+			return fmt.Errorf("failed to start ssh-agent: %w", err)
+		}
+		if m := agentPIDPattern.FindSubmatch(output); m != nil {
+			if err := os.WriteFile(agentPIDPath(socketPath), m[1], 0600); err != nil {
+				return fmt.Errorf("failed to record agent pid: %w", err)
+			}
+		}
+	}
+
+	// ssh-add may need to prompt for a passphrase
+	cmd := exec.Command("ssh-add", keyPath)
+	cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+socketPath)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to add key to agent: %w", err)
+	}
+	return nil
+}
+
+// StopAgent kills the dedicated agent on socketPath and removes its runtime files
+func StopAgent(socketPath string) error {
+	pidPath := agentPIDPath(socketPath)
+	pid, err := os.ReadFile(pidPath)
+	if err == nil {
+		cmd := exec.Command("ssh-agent", "-k")
+		cmd.Env = append(os.Environ(),
+			"SSH_AUTH_SOCK="+socketPath,
+			"SSH_AGENT_PID="+strings.TrimSpace(string(pid)))
+		if err := cmd.Run(); err != nil && AgentRunning(socketPath) {
+			return fmt.Errorf("failed to stop ssh-agent: %w", err)
+		}
+	}
+
+	os.Remove(pidPath)
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove agent socket: %w", err)
+	}
+	return nil
+}
+
+// RemoveAgentKey unloads keyPath from the agent on socketPath
+func RemoveAgentKey(socketPath, keyPath string) error {
+	cmd := exec.Command("ssh-add", "-d", publicKeyPath(keyPath))
+	cmd.Env = append(os.Environ(), "SSH_AUTH_SOCK="+socketPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove key from agent: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
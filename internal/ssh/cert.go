@@ -0,0 +1,136 @@
+package ssh
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ssh-keygen -L prints validity times in local time without a zone
+const certTimeLayout = "2006-01-02T15:04:05"
+
+// CertInfo describes an OpenSSH user certificate
+type CertInfo struct {
+	Path        string
+	KeyID       string
+	Serial      string
+	SigningCA   string
+	Principals  []string
+	ValidAfter  time.Time // zero when valid from the beginning of time
+	ValidBefore time.Time // zero when valid forever
+}
+
+// ExpiresWithin reports whether the certificate expires (or has expired) before now+d
+func (c *CertInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !c.ValidBefore.IsZero() && c.ValidBefore.Before(now.Add(d))
+}
+
+// Expired reports whether the certificate is no longer valid at now
+func (c *CertInfo) Expired(now time.Time) bool {
+	return !c.ValidBefore.IsZero() && !now.Before(c.ValidBefore)
+}
+
+// CertificatePath returns where ssh-keygen -s writes the certificate for keyPath
+func CertificatePath(keyPath string) string {
+	return strings.TrimSuffix(keyPath, ".pub") + "-cert.pub"
+}
+
+// SignCertificate signs keyPath's public key with caKeyPath, writing <key>-cert.pub.
+// validity uses ssh-keygen -V syntax (e.g. "+52w"); empty means forever.
+func SignCertificate(caKeyPath, keyPath, keyID string, principals []string, validity string) (string, error) {
+	args := []string{"-s", caKeyPath, "-I", keyID}
+	if len(principals) > 0 {
+		args = append(args, "-n", strings.Join(principals, ","))
+	}
+	if validity != "" {
+		args = append(args, "-V", validity)
+	}
+	args = append(args, publicKeyPath(keyPath))
+
+	// A passphrase prompt for the CA key goes straight to the terminal
+	if output, err := exec.Command("ssh-keygen", args...).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("ssh-keygen -s failed: %s", strings.TrimSpace(string(output)))
+	}
+	return CertificatePath(keyPath), nil
+}
+
+// InspectCertificate reads a certificate's metadata with ssh-keygen -L
+func InspectCertificate(certPath string) (*CertInfo, error) {
+	output, err := exec.Command("ssh-keygen", "-L", "-f", certPath).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %s: %s", certPath, strings.TrimSpace(string(output)))
+	}
+	info, err := parseCertificateListing(string(output))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", certPath, err)
+	}
+	info.Path = certPath
+	return info, nil
+}
+
+func parseCertificateListing(listing string) (*CertInfo, error) {
+	info := &CertInfo{}
+	inPrincipals := false
+
+	for _, line := range strings.Split(listing, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+
+		if inPrincipals {
+			// Principals are listed one per line until the next section
+			if strings.HasPrefix(line, "Critical Options:") || strings.HasPrefix(line, "Extensions:") {
+				inPrincipals = false
+			} else {
+				if line != "" {
+					info.Principals = append(info.Principals, line)
+				}
+				continue
+			}
+		}
+
+		switch key {
+		case "Key ID":
+			info.KeyID = strings.Trim(value, `"`)
+		case "Serial":
+			info.Serial = value
+		case "Signing CA":
+			info.SigningCA = value
+		case "Principals":
+			inPrincipals = value != "(none)"
+		case "Valid":
+			if err := parseValidity(value, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseValidity handles "forever", "from X to Y", "after X" and "before Y"
+func parseValidity(value string, info *CertInfo) error {
+	fields := strings.Fields(value)
+	parse := func(s string) (time.Time, error) {
+		return time.ParseInLocation(certTimeLayout, s, time.Local)
+	}
+
+	var err error
+	switch {
+	case value == "forever":
+	case len(fields) == 4 && fields[0] == "from" && fields[2] == "to":
+		if info.ValidAfter, err = parse(fields[1]); err == nil {
+			info.ValidBefore, err = parse(fields[3])
+		}
+	case len(fields) == 2 && fields[0] == "after":
+		info.ValidAfter, err = parse(fields[1])
+	case len(fields) == 2 && fields[0] == "before":
+		info.ValidBefore, err = parse(fields[1])
+	default:
+		return fmt.Errorf("unrecognised validity %q", value)
+	}
+	if err != nil {
+		return fmt.Errorf("unrecognised validity %q: %w", value, err)
+	}
+	return nil
+}
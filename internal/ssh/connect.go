@@ -0,0 +1,81 @@
+package ssh
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// DefaultSSHCommand is used by CheckConnection when no command is configured
+const DefaultSSHCommand = "ssh"
+
+var (
+	githubGreeting = regexp.MustCompile(`Hi ([A-Za-z0-9][A-Za-z0-9-]*)!`)
+	gitlabGreeting = regexp.MustCompile(`Welcome to GitLab, @([A-Za-z0-9_.-]+)!`)
+)
+
+// ConnectionResult is the outcome of an authentication test against a git host
+type ConnectionResult struct {
+	Authenticated bool
+	User          string // account the host says the key belongs to
+	Output        string
+}
+
+// ParseGreeting extracts the authenticated username from a git host's "ssh -T" banner
+func ParseGreeting(output string) (string, bool) {
+	for _, pattern := range []*regexp.Regexp{githubGreeting, gitlabGreeting} {
+		if m := pattern.FindStringSubmatch(output); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
+
+// CheckConnection runs "<sshCommand> -T git@<hostAlias>" and parses the greeting.
+// sshCommand is split on whitespace so a wrapper with arguments (e.g. "ssh -F /tmp/config") works.
+func CheckConnection(sshCommand, hostAlias string) (*ConnectionResult, error) {
+	parts := connectionCommand(sshCommand, hostAlias)
+	args := parts[1:]
+
+	// GitHub closes the session with exit status 1 even on success, so the
+	// banner is what counts, not the exit code
+	output, err := exec.Command(parts[0], args...).CombinedOutput()
+	if _, isExit := err.(*exec.ExitError); err != nil && !isExit {
+		return nil, fmt.Errorf("failed to run %s: %w", parts[0], err)
+	}
+
+	result := &ConnectionResult{Output: strings.TrimSpace(string(output))}
+	result.User, result.Authenticated = ParseGreeting(result.Output)
+	return result, nil
+}
+
+// connectionCommand builds the ssh command line CheckConnection runs. When the
+// SSH config was relocated (ConfigFile), ssh is pointed at it with -F unless
+// sshCommand already picks a config file.
+func connectionCommand(sshCommand, hostAlias string) []string {
+	parts := strings.Fields(sshCommand)
+	if len(parts) == 0 {
+		parts = []string{DefaultSSHCommand}
+	}
+	command := append([]string{}, parts...)
+	if ConfigFile != "" && !containsArg(parts[1:], "-F") {
+		command = append(command, "-F", ConfigFile)
+	}
+	return append(command,
+		"-T",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=10",
+		"git@"+hostAlias)
+}
+
+// containsArg reports whether args has option arg, alone ("-F path") or
+// joined with its value ("-Fpath")
+func containsArg(args []string, arg string) bool {
+	for _, a := range args {
+		if strings.HasPrefix(a, arg) {
+			return true
+		}
+	}
+	return false
+}
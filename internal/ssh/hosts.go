@@ -0,0 +1,218 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// KnownGitHosts are the hostnames treated as git hosts when importing SSH config entries
+var KnownGitHosts = []string{"github.com", "ssh.github.com", "gitlab.com", "bitbucket.org"}
+
+// HostEntry is a Host block from the user's own (non-gitx) SSH config
+type HostEntry struct {
+	Host         string
+	HostName     string
+	User         string
+	IdentityFile string
+	// Options are the block's other directives, as "Keyword value" lines
+	Options   []string
+	startLine int
+	endLine   int // exclusive
+}
+
+// IsGitHost reports whether the entry points at one of KnownGitHosts
+func (h HostEntry) IsGitHost() bool {
+	name := strings.ToLower(h.HostName)
+	if name == "" {
+		name = strings.ToLower(h.Host)
+	}
+	for _, known := range KnownGitHosts {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// managedDirectives are set by the gitx managed block itself, so a Host block
+// using them can't be moved into it unchanged
+var managedDirectives = map[string]bool{"identityfile": true, "identitiesonly": true, "hostkeyalias": true, "userknownhostsfile": true, "identityagent": true, "certificatefile": true}
+
+// UnmovableOptions returns the directives of the entry the managed block
+// can't express: a User other than git, and the ones it sets itself
+func (h HostEntry) UnmovableOptions() []string {
+	var unmovable []string
+	if h.User != "" && h.User != "git" {
+		unmovable = append(unmovable, "User "+h.User)
+	}
+	for _, option := range h.Options {
+		if keyword, _ := splitDirective(option); managedDirectives[keyword] {
+			unmovable = append(unmovable, option)
+		}
+	}
+	return unmovable
+}
+
+// splitDirective splits an ssh_config line into keyword and argument.
+// Both "Keyword value" and "Keyword=value" forms are accepted.
+func splitDirective(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return strings.ToLower(line), ""
+	}
+	keyword := strings.ToLower(line[:idx])
+	value := strings.TrimSpace(line[idx:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return keyword, strings.Trim(value, `"`)
+}
+
+// ParseHostEntries returns the Host blocks outside the gitx managed block.
+// Blocks with several or wildcard patterns are skipped since they can't map to one identity.
+func ParseHostEntries(content string) []HostEntry {
+	var entries []HostEntry
+	lines := strings.Split(content, "\n")
+	inManagedBlock := false
+	var current *HostEntry
+
+	flush := func(end int) {
+		if current != nil {
+			current.endLine = end
+			entries = append(entries, *current)
+			current = nil
+		}
+	}
+
+	for i, line := range lines {
+		if strings.Contains(line, SSHConfigMarkerBegin) {
+			flush(i)
+			inManagedBlock = true
+			continue
+		}
+		if strings.Contains(line, SSHConfigMarkerEnd) {
+			inManagedBlock = false
+			continue
+		}
+		if inManagedBlock {
+			continue
+		}
+
+		keyword, value := splitDirective(line)
+		switch keyword {
+		case "host":
+			flush(i)
+			if !strings.ContainsAny(value, " \t*?!") {
+				current = &HostEntry{Host: value, startLine: i}
+			}
+		case "match":
+			flush(i)
+		case "hostname":
+			if current != nil {
+				current.HostName = value
+			}
+		case "user":
+			if current != nil {
+				current.User = value
+			}
+		case "identityfile":
+			// ssh uses every IdentityFile; the first one is the identity's key
+			if current != nil && current.IdentityFile == "" {
+				current.IdentityFile = value
+			} else if current != nil {
+				current.Options = append(current.Options, strings.TrimSpace(line))
+			}
+		case "":
+		default:
+			if current != nil {
+				current.Options = append(current.Options, strings.TrimSpace(line))
+			}
+		}
+	}
+	flush(len(lines))
+
+	return entries
+}
+
+// ListUnmanagedGitHosts returns Host entries from ~/.ssh/config that point at a git
+// host with an IdentityFile and are not managed by gitx
+func ListUnmanagedGitHosts() ([]HostEntry, error) {
+	configPath, err := GetSSHConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	var result []HostEntry
+	for _, entry := range ParseHostEntries(string(data)) {
+		if entry.IsGitHost() && entry.IdentityFile != "" {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// RemoveHostEntry deletes a user-written Host block (outside the managed block) from ~/.ssh/config
+func RemoveHostEntry(host string) error {
+	configPath, err := GetSSHConfigPath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	content := string(data)
+	var target *HostEntry
+	for _, entry := range ParseHostEntries(content) {
+		if entry.Host == host {
+			e := entry
+			target = &e
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no Host entry for %s in SSH config", host)
+	}
+
+	if _, err := BackupSSHConfig(); err != nil {
+		return fmt.Errorf("failed to backup SSH config: %w", err)
+	}
+
+	lines := strings.Split(content, "\n")
+	lines = append(lines[:target.startLine], lines[target.endLine:]...)
+
+	tempPath := configPath + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		return fmt.Errorf("failed to write temp config: %w", err)
+	}
+	if err := os.Rename(tempPath, configPath); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return nil
+}
+
+// ExpandPath expands a leading ~ in an ssh_config path
+func ExpandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	return filepath.Join(usr.HomeDir, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,198 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyFingerprint returns the SHA256 fingerprint of a key (e.g. "SHA256:abc...")
+func KeyFingerprint(keyPath string) (string, error) {
+	output, err := exec.Command("ssh-keygen", "-l", "-E", "sha256", "-f", publicKeyPath(keyPath)).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read fingerprint of %s: %w", keyPath, err)
+	}
+	// Format: "256 SHA256:xxxx comment (ED25519)"
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return "", fmt.Errorf("unexpected ssh-keygen output: %s", strings.TrimSpace(string(output)))
+	}
+	return fields[1], nil
+}
+
+// publicKeyPath returns the .pub path for a private key path
+func publicKeyPath(keyPath string) string {
+	if strings.HasSuffix(keyPath, ".pub") {
+		return keyPath
+	}
+	return keyPath + ".pub"
+}
+
+// KeyInfo describes an SSH key pair on disk
+type KeyInfo struct {
+	Path        string      `json:"path"`
+	Type        string      `json:"type"`
+	Bits        int         `json:"bits"`
+	Fingerprint string      `json:"fingerprint"`
+	Comment     string      `json:"comment"`
+	ModTime     time.Time   `json:"modified"`
+	Mode        os.FileMode `json:"mode"`
+	// PubMatches is nil when it can't be checked (e.g. the key is passphrase-protected)
+	PubMatches          *bool `json:"pub_matches"`
+	PassphraseProtected bool  `json:"passphrase_protected"`
+}
+
+// LoosePermissions reports whether the private key is readable or writable by anyone but its owner
+func (k *KeyInfo) LoosePermissions() bool {
+	return k.Mode.Perm()&0077 != 0
+}
+
+// InspectKey gathers type, size, fingerprint, permissions and consistency details for a private key
+func InspectKey(keyPath string) (*KeyInfo, error) {
+	stat, err := os.Stat(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	info := &KeyInfo{
+		Path:    keyPath,
+		ModTime: stat.ModTime(),
+		Mode:    stat.Mode().Perm(),
+	}
+
+	// ssh-keygen -l prefers the .pub next to the key when there is one
+	output, err := exec.Command("ssh-keygen", "-l", "-E", "sha256", "-f", keyPath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", keyPath, err)
+	}
+	// Format: "256 SHA256:xxxx comment with spaces (ED25519)"
+	fields := strings.Fields(strings.TrimSpace(string(output)))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected ssh-keygen output: %s", strings.TrimSpace(string(output)))
+	}
+	info.Bits, _ = strconv.Atoi(fields[0])
+	info.Fingerprint = fields[1]
+	info.Type = strings.Trim(fields[len(fields)-1], "()")
+	info.Comment = strings.Join(fields[2:len(fields)-1], " ")
+	if info.Comment == "no comment" {
+		info.Comment = ""
+	}
+
+	pubBlob, protected, err := readPrivateKeyHeader(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	info.PassphraseProtected = protected
+	if pubBlob != "" {
+		// Report the private key's own fingerprint in case the .pub doesn't match it
+		if fp := blobFingerprint(pubBlob); fp != "" {
+			info.Fingerprint = fp
+		}
+		matches := false
+		if pub, err := os.ReadFile(keyPath + ".pub"); err == nil {
+			matches = publicKeyBlob(string(pub)) == pubBlob
+		}
+		info.PubMatches = &matches
+	}
+
+	return info, nil
+}
+
+// publicKeyBlob returns the "type base64" part of an authorized_keys style line, dropping the comment
+func publicKeyBlob(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[0] + " " + fields[1]
+}
+
+// blobFingerprint computes the OpenSSH SHA256 fingerprint of a "type base64" public key blob
+func blobFingerprint(blob string) string {
+	fields := strings.Fields(blob)
+	if len(fields) != 2 {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// readPrivateKeyHeader reports whether a private key is encrypted and, when it can be
+// determined without the passphrase, its public key as a "type base64" blob.
+// OpenSSH-format keys carry the public key in clear; legacy PEM keys need ssh-keygen.
+func readPrivateKeyHeader(keyPath string) (string, bool, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", false, fmt.Errorf("%s is not a PEM encoded private key", keyPath)
+	}
+
+	if block.Type != "OPENSSH PRIVATE KEY" {
+		if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+			return "", true, nil
+		}
+		derived, err := exec.Command("ssh-keygen", "-y", "-P", "", "-f", keyPath).Output()
+		if err != nil {
+			// Usually loose permissions; the public key just can't be checked
+			return "", false, nil
+		}
+		return publicKeyBlob(string(derived)), false, nil
+	}
+
+	// openssh-key-v1: magic, ciphername, kdfname, kdfoptions, key count, public key
+	const magic = "openssh-key-v1\x00"
+	rest := block.Bytes
+	if !bytes.HasPrefix(rest, []byte(magic)) {
+		return "", false, fmt.Errorf("%s has an unknown key format", keyPath)
+	}
+	rest = rest[len(magic):]
+
+	var fields [3][]byte
+	for i := range fields {
+		if fields[i], rest, err = readSSHString(rest); err != nil {
+			return "", false, fmt.Errorf("%s is malformed: %w", keyPath, err)
+		}
+	}
+	if len(rest) < 4 {
+		return "", false, fmt.Errorf("%s is malformed: truncated", keyPath)
+	}
+	rest = rest[4:] // number of keys, always 1 in practice
+	pubKey, _, err := readSSHString(rest)
+	if err != nil {
+		return "", false, fmt.Errorf("%s is malformed: %w", keyPath, err)
+	}
+	keyType, _, err := readSSHString(pubKey)
+	if err != nil {
+		return "", false, fmt.Errorf("%s is malformed: %w", keyPath, err)
+	}
+
+	protected := string(fields[0]) != "none"
+	return string(keyType) + " " + base64.StdEncoding.EncodeToString(pubKey), protected, nil
+}
+
+// readSSHString reads a uint32 length-prefixed string as used in the SSH wire format
+func readSSHString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("truncated")
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(n) {
+		return nil, nil, fmt.Errorf("truncated")
+	}
+	return data[4 : 4+n], data[4+n:], nil
+}
@@ -0,0 +1,155 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KnownHostsFile is the gitx-managed known_hosts file. When set, managed host entries
+// list it as UserKnownHostsFile ahead of the user's own ~/.ssh/known_hosts.
+var KnownHostsFile string
+
+// DefaultPinnedHostKeys are GitHub's published host keys
+// (https://docs.github.com/en/authentication/keeping-your-account-and-data-secure/githubs-ssh-key-fingerprints)
+var DefaultPinnedHostKeys = map[string][]string{
+	"github.com": {
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+		"ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBEmKSENjQEezOmxkZMy7opKgwFB9nkt5YRrYMjNuG5N87uRgg6CLrbo5wAdT/y6v0mKV0U2w0WZ2YB/++Tpockg=",
+		"ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABgQCj7ndNxQowgcQnjshcLrqPEiiphnt+VTTvDP6mHBL9j1aNUkY4Ue1gvwnGLVlOhGeYrnZaMgRK6+PKCUXaDbC7qtbW8gIkhL7aGCsOr/C56SJMy/BCZfxd1nWzAOxSDPgVsmerOBYfNqltV9/hWCqBywINIR+5dIg6JTJ72pcEpEjcYgXkE2YEFXV1JHnsKgbLWNlhScqb2UmyRkQyytRLtL+38TGxkxCflmO+5Z8CSSNY7GidjMIZ7Q4zMjA2n1nGrlTDkzwDCsw+wqFPGQA179cnfGWOWRVruj16z6XyvxvjJwbz0wQZ75XK5tKSb7FNyeIEs4TT4jk+S4dhPeAUC5y+bDYirYgM4GC7uEnztnZyaVWQ7B381AK4Qdrwt51ZqExKbQpTUNn+EjqoTwvqNj4kqx5QUCI0ThS/YkOxJCXmPUWZbhjpCg56i+2aB6CmK2JGhn57K5mj0MNdBXA4/WnwH6XoPWJzK5Nyu2zB3nAZp+S5hpQs+p1vN1/wsjk=",
+	},
+}
+
+// HostKey is one entry of a known_hosts file
+type HostKey struct {
+	Host        string
+	Key         string // "type base64"
+	Fingerprint string
+}
+
+// WriteKnownHosts writes keys (hostname -> "type base64" keys) as a known_hosts file
+func WriteKnownHosts(path string, keys map[string][]string) error {
+	hosts := make([]string, 0, len(keys))
+	for host := range keys {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	b.WriteString("# Managed by gitx. Changes will be overwritten by 'gitx known-hosts update'.\n")
+	for _, host := range hosts {
+		for _, key := range keys[host] {
+			b.WriteString(host + " " + publicKeyBlob(key) + "\n")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// ReadKnownHosts parses a known_hosts file. Hashed and marker (@cert-authority,
+// @revoked) lines are skipped.
+func ReadKnownHosts(path string) ([]HostKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseHostKeys(file)
+}
+
+func parseHostKeys(file io.Reader) ([]HostKey, error) {
+	var keys []HostKey
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "|") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		key := fields[1] + " " + fields[2]
+		for _, host := range strings.Split(fields[0], ",") {
+			keys = append(keys, HostKey{Host: host, Key: key, Fingerprint: blobFingerprint(key)})
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// ScanHostKeys fetches the current host keys of host with ssh-keyscan
+func ScanHostKeys(host string) ([]HostKey, error) {
+	cmd := exec.Command("ssh-keyscan", "-T", "10", host)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ssh-keyscan %s failed: %w", host, err)
+	}
+	keys, err := parseHostKeys(strings.NewReader(string(output)))
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("ssh-keyscan returned no keys for %s", host)
+	}
+	return keys, nil
+}
+
+// ParseFingerprintList reads a list of expected fingerprints, one per line, as
+// "SHA256:..." (any host), "<host> SHA256:..." or ssh-keygen -l output
+// ("256 SHA256:... <host> (ED25519)"). The result maps host ("" for any) to fingerprints.
+func ParseFingerprintList(content string) (map[string][]string, error) {
+	result := map[string][]string{}
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		host, fp := "", ""
+		switch {
+		case strings.HasPrefix(fields[0], "SHA256:"):
+			fp = fields[0]
+		case len(fields) >= 2 && strings.HasPrefix(fields[1], "SHA256:"):
+			fp = fields[1]
+			if _, err := strconv.Atoi(fields[0]); err != nil {
+				host = fields[0]
+			} else if len(fields) >= 3 && !strings.HasPrefix(fields[2], "(") {
+				host = fields[2]
+			}
+		default:
+			return nil, fmt.Errorf("line %d: expected a SHA256 fingerprint: %q", i+1, line)
+		}
+		result[host] = append(result[host], fp)
+	}
+	return result, nil
+}
+
+// FingerprintAllowed reports whether fp is listed for host (or for any host)
+func FingerprintAllowed(expected map[string][]string, host, fp string) bool {
+	for _, list := range [][]string{expected[host], expected[""]} {
+		for _, candidate := range list {
+			if candidate == fp {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,12 +1,14 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -14,7 +16,17 @@ const (
 	SSHConfigMarkerEnd   = "# END gitx managed"
 )
 
+// ConfigFile and KeyDir relocate the SSH config gitx manages and the
+// directory it keeps keys in; empty means ~/.ssh/config and ~/.ssh
+var (
+	ConfigFile string
+	KeyDir     string
+)
+
 func GetSSHConfigPath() (string, error) {
+	if ConfigFile != "" {
+		return ConfigFile, nil
+	}
 	usr, err := user.Current()
 	if err != nil {
 		return "", fmt.Errorf("failed to get current user: %w", err)
@@ -22,41 +34,182 @@ func GetSSHConfigPath() (string, error) {
 	return filepath.Join(usr.HomeDir, ".ssh", "config"), nil
 }
 
-func GenerateSSHKey(identityAlias string) (string, error) {
-	usr, err := user.Current()
+// KeyPath returns where gitx keeps the SSH key of an identity
+func KeyPath(identityAlias string) (string, error) {
+	sshDir, err := getSSHDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get current user: %w", err)
+		return "", err
 	}
+	return filepath.Join(sshDir, fmt.Sprintf("gitx_%s", identityAlias)), nil
+}
 
-	sshDir := filepath.Join(usr.HomeDir, ".ssh")
-	if err := os.MkdirAll(sshDir, 0700); err != nil {
-		return "", fmt.Errorf("failed to create .ssh directory: %w", err)
+func GenerateSSHKey(identityAlias string) (string, error) {
+	keyPath, err := KeyPath(identityAlias)
+	if err != nil {
+		return "", err
 	}
 
-	keyPath := filepath.Join(sshDir, fmt.Sprintf("gitx_%s", identityAlias))
-
 	// Check if key already exists
 	if _, err := os.Stat(keyPath); err == nil {
 		return keyPath, nil
 	}
 
-	// Generate SSH key
-	cmd := exec.Command("ssh-keygen", "-t", "ed25519", "-f", keyPath, "-N", "", "-C", fmt.Sprintf("gitx-%s", identityAlias))
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to generate SSH key: %w", err)
+	if err := generateKey(keyPath, identityAlias); err != nil {
+		return "", err
 	}
+	return keyPath, nil
+}
 
+// RotatedKeyPath returns where a key rotated at now goes: next to the current
+// one, named gitx_<alias>_<date> (or _<date>-<time> when that is taken)
+func RotatedKeyPath(identityAlias string, now time.Time) (string, error) {
+	basePath, err := KeyPath(identityAlias)
+	if err != nil {
+		return "", err
+	}
+	keyPath := basePath + "_" + now.Format("20060102")
+	if _, err := os.Stat(keyPath); err == nil {
+		keyPath = basePath + "_" + now.Format("20060102-150405")
+	}
 	return keyPath, nil
 }
 
+// GenerateRotatedSSHKey generates a fresh key for an identity at RotatedKeyPath,
+// so the previous key can be kept during a grace period
+func GenerateRotatedSSHKey(identityAlias string, now time.Time) (string, error) {
+	keyPath, err := RotatedKeyPath(identityAlias, now)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(keyPath); err == nil {
+		return "", fmt.Errorf("key already exists: %s", keyPath)
+	}
+
+	if err := generateKey(keyPath, identityAlias); err != nil {
+		return "", err
+	}
+	return keyPath, nil
+}
+
+func getSSHDir() (string, error) {
+	sshDir := KeyDir
+	if sshDir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current user: %w", err)
+		}
+		sshDir = filepath.Join(usr.HomeDir, ".ssh")
+	}
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create .ssh directory: %w", err)
+	}
+	return sshDir, nil
+}
+
+func generateKey(keyPath, identityAlias string) error {
+	cmd := exec.Command("ssh-keygen", "-t", "ed25519", "-f", keyPath, "-N", "", "-C", fmt.Sprintf("gitx-%s", identityAlias))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to generate SSH key: %w", err)
+	}
+	return nil
+}
+
 // SSHIdentity represents an SSH host alias and key path pair
 type SSHIdentity struct {
-	HostAlias string
-	KeyPath   string
+	HostAlias       string
+	HostName        string // defaults to github.com
+	KeyPath         string
+	IdentityAgent   string // optional dedicated agent socket
+	CertificateFile string // optional user certificate
+	// Options are further "Keyword value" directives, e.g. "Port 443", carried
+	// over from an imported Host block
+	Options []string
 }
 
 // AddSSHConfigEntry adds or updates an SSH config entry, preserving all existing gitx-managed entries
 func AddSSHConfigEntry(hostAlias, keyPath string) error {
+	return rewriteManagedBlock(func(identities []SSHIdentity) []SSHIdentity {
+		for i, id := range identities {
+			if id.HostAlias == hostAlias {
+				identities[i].KeyPath = keyPath
+				return identities
+			}
+		}
+		return append(identities, SSHIdentity{
+			HostAlias: hostAlias,
+			KeyPath:   keyPath,
+		})
+	})
+}
+
+// UpsertSSHConfigEntry adds entry to the managed block, replacing any entry with the same host alias
+func UpsertSSHConfigEntry(entry SSHIdentity) error {
+	return rewriteManagedBlock(func(identities []SSHIdentity) []SSHIdentity {
+		for i, id := range identities {
+			if id.HostAlias == entry.HostAlias {
+				if entry.Options == nil {
+					entry.Options = id.Options
+				}
+				identities[i] = entry
+				return identities
+			}
+		}
+		return append(identities, entry)
+	})
+}
+
+// SetIdentityAgent sets or clears (socketPath == "") the IdentityAgent of a managed host entry
+func SetIdentityAgent(hostAlias, socketPath string) error {
+	return updateManagedEntry(hostAlias, func(id *SSHIdentity) {
+		id.IdentityAgent = socketPath
+	})
+}
+
+// SetCertificateFile sets or clears (certPath == "") the CertificateFile of a managed host entry
+func SetCertificateFile(hostAlias, certPath string) error {
+	return updateManagedEntry(hostAlias, func(id *SSHIdentity) {
+		id.CertificateFile = certPath
+	})
+}
+
+// RenameSSHConfigEntry moves the managed entry for oldAlias to newAlias in a
+// single rewrite, applying update (may be nil) to it on the way
+func RenameSSHConfigEntry(oldAlias, newAlias string, update func(*SSHIdentity)) error {
+	return updateManagedEntry(oldAlias, func(id *SSHIdentity) {
+		if update != nil {
+			update(id)
+		}
+		id.HostAlias = newAlias
+	})
+}
+
+// ErrNoManagedEntry is returned when the managed block has no entry for a host alias
+var ErrNoManagedEntry = errors.New("no managed SSH config entry")
+
+// updateManagedEntry applies update to the managed entry for hostAlias
+func updateManagedEntry(hostAlias string, update func(*SSHIdentity)) error {
+	found := false
+	err := rewriteManagedBlock(func(identities []SSHIdentity) []SSHIdentity {
+		for i := range identities {
+			if identities[i].HostAlias == hostAlias {
+				update(&identities[i])
+				found = true
+			}
+		}
+		return identities
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w for %s", ErrNoManagedEntry, hostAlias)
+	}
+	return nil
+}
+
+// rewriteManagedBlock backs up the SSH config, applies update to the parsed
+// managed entries and atomically writes the result back
+func rewriteManagedBlock(update func([]SSHIdentity) []SSHIdentity) error {
 	configPath, err := GetSSHConfigPath()
 	if err != nil {
 		return err
@@ -73,37 +226,24 @@ func AddSSHConfigEntry(hostAlias, keyPath string) error {
 		existingContent = string(data)
 	}
 
-	// Parse existing managed entries
-	existingIdentities := parseManagedBlock(existingContent)
-	
-	// Add or update the new identity
-	found := false
-	for i, id := range existingIdentities {
-		if id.HostAlias == hostAlias {
-			existingIdentities[i].KeyPath = keyPath
-			found = true
-			break
-		}
-	}
-	if !found {
-		existingIdentities = append(existingIdentities, SSHIdentity{
-			HostAlias: hostAlias,
-			KeyPath:   keyPath,
-		})
-	}
+	// Parse existing managed entries and apply the change
+	identities := update(parseManagedBlock(existingContent))
 
 	// Remove existing managed block
-	existingContent = removeManagedBlock(existingContent)
+	newContent := removeManagedBlock(existingContent)
 
-	// Build new managed block with all identities
-	managedBlock := buildManagedBlockFromIdentities(existingIdentities)
+	// If there are remaining identities, rebuild the managed block
+	if len(identities) > 0 {
+		managedBlock := buildManagedBlockFromIdentities(identities)
+		if !strings.HasSuffix(newContent, "\n") && newContent != "" {
+			newContent += "\n"
+		}
+		newContent += managedBlock + "\n"
+	}
 
-	// Append managed block
-	newContent := existingContent
-	if !strings.HasSuffix(newContent, "\n") && newContent != "" {
-		newContent += "\n"
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("failed to create .ssh directory: %w", err)
 	}
-	newContent += managedBlock + "\n"
 
 	// Write to temp file first
 	tempPath := configPath + ".tmp"
@@ -140,59 +280,104 @@ func buildManagedBlock(hostAlias, keyPath string) string {
 func buildManagedBlockFromIdentities(identities []SSHIdentity) string {
 	block := fmt.Sprintf("%s\n", SSHConfigMarkerBegin)
 	for _, id := range identities {
+		hostName := id.HostName
+		if hostName == "" {
+			hostName = "github.com"
+		}
 		block += fmt.Sprintf("Host %s\n", id.HostAlias)
-		block += fmt.Sprintf("  HostName github.com\n")
+		block += fmt.Sprintf("  HostName %s\n", hostName)
 		block += fmt.Sprintf("  User git\n")
 		block += fmt.Sprintf("  IdentityFile %s\n", id.KeyPath)
 		block += fmt.Sprintf("  IdentitiesOnly yes\n")
+		// Key host keys by the real hostname so every alias shares one known_hosts entry
+		block += fmt.Sprintf("  HostKeyAlias %s\n", hostName)
+		if KnownHostsFile != "" {
+			block += fmt.Sprintf("  UserKnownHostsFile %s ~/.ssh/known_hosts\n", quoteConfigValue(KnownHostsFile))
+		}
+		if id.IdentityAgent != "" {
+			block += fmt.Sprintf("  IdentityAgent %s\n", id.IdentityAgent)
+		}
+		if id.CertificateFile != "" {
+			block += fmt.Sprintf("  CertificateFile %s\n", id.CertificateFile)
+		}
+		for _, option := range id.Options {
+			block += fmt.Sprintf("  %s\n", option)
+		}
 		block += "\n"
 	}
 	block += fmt.Sprintf("%s\n", SSHConfigMarkerEnd)
 	return block
 }
 
+// quoteConfigValue quotes an ssh_config argument that contains whitespace
+func quoteConfigValue(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+// RefreshManagedBlock rewrites the managed block so every entry picks up the
+// current defaults (e.g. HostKeyAlias and the gitx known_hosts file)
+func RefreshManagedBlock() error {
+	configPath, err := GetSSHConfigPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil
+	}
+	return rewriteManagedBlock(func(identities []SSHIdentity) []SSHIdentity {
+		return identities
+	})
+}
+
+// generatedDirectives are written for every managed entry from the fields above,
+// so they are not kept as Options when the block is parsed
+var generatedDirectives = map[string]bool{"user": true, "identitiesonly": true, "hostkeyalias": true, "userknownhostsfile": true}
+
 func parseManagedBlock(content string) []SSHIdentity {
 	var identities []SSHIdentity
 	lines := strings.Split(content, "\n")
 	inManagedBlock := false
-	var currentHost string
-	var currentKeyPath string
+	var current SSHIdentity
+
+	flush := func() {
+		if current.HostAlias != "" && current.KeyPath != "" {
+			identities = append(identities, current)
+		}
+		current = SSHIdentity{}
+	}
 
-	for i, line := range lines {
+	for _, line := range lines {
 		if strings.Contains(line, SSHConfigMarkerBegin) {
 			inManagedBlock = true
 			continue
 		}
 		if strings.Contains(line, SSHConfigMarkerEnd) {
 			// Save last identity if any
-			if currentHost != "" && currentKeyPath != "" {
-				identities = append(identities, SSHIdentity{
-					HostAlias: currentHost,
-					KeyPath:   currentKeyPath,
-				})
-			}
+			flush()
 			inManagedBlock = false
-			currentHost = ""
-			currentKeyPath = ""
 			continue
 		}
 		if inManagedBlock {
 			line = strings.TrimSpace(line)
 			if strings.HasPrefix(line, "Host ") {
 				// Save previous identity if any
-				if currentHost != "" && currentKeyPath != "" {
-					identities = append(identities, SSHIdentity{
-						HostAlias: currentHost,
-						KeyPath:   currentKeyPath,
-					})
-				}
-				currentHost = strings.TrimPrefix(line, "Host ")
-				currentKeyPath = ""
+				flush()
+				current.HostAlias = strings.TrimPrefix(line, "Host ")
+			} else if strings.HasPrefix(line, "HostName ") {
+				current.HostName = strings.TrimPrefix(line, "HostName ")
 			} else if strings.HasPrefix(line, "IdentityFile ") {
-				currentKeyPath = strings.TrimPrefix(line, "IdentityFile ")
+				current.KeyPath = strings.TrimPrefix(line, "IdentityFile ")
+			} else if strings.HasPrefix(line, "IdentityAgent ") {
+				current.IdentityAgent = strings.TrimPrefix(line, "IdentityAgent ")
+			} else if strings.HasPrefix(line, "CertificateFile ") {
+				current.CertificateFile = strings.TrimPrefix(line, "CertificateFile ")
+			} else if keyword, _ := splitDirective(line); keyword != "" && !generatedDirectives[keyword] && current.HostAlias != "" {
+				current.Options = append(current.Options, line)
 			}
 		}
-		_ = i // avoid unused variable
 	}
 
 	return identities
@@ -236,57 +421,18 @@ func RemoveSSHConfigEntry(hostAlias string) error {
 	if err != nil {
 		return err
 	}
-
-	// Backup before making changes
-	if _, err := BackupSSHConfig(); err != nil {
-		return fmt.Errorf("failed to backup SSH config: %w", err)
-	}
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
+	if _, err := os.Stat(configPath); err != nil {
 		return err
 	}
 
-	// Parse existing managed entries
-	existingIdentities := parseManagedBlock(string(data))
-	
-	// Remove the specified host alias
-	filteredIdentities := []SSHIdentity{}
-	for _, id := range existingIdentities {
-		if id.HostAlias != hostAlias {
-			filteredIdentities = append(filteredIdentities, id)
-		}
-	}
-
-	// Remove existing managed block
-	content := removeManagedBlock(string(data))
-
-	// If there are remaining identities, rebuild the managed block
-	if len(filteredIdentities) > 0 {
-		managedBlock := buildManagedBlockFromIdentities(filteredIdentities)
-		if !strings.HasSuffix(content, "\n") && content != "" {
-			content += "\n"
+	return rewriteManagedBlock(func(identities []SSHIdentity) []SSHIdentity {
+		// Remove the specified host alias
+		filtered := []SSHIdentity{}
+		for _, id := range identities {
+			if id.HostAlias != hostAlias {
+				filtered = append(filtered, id)
+			}
 		}
-		content += managedBlock + "\n"
-	}
-
-	// Write to temp file first
-	tempPath := configPath + ".tmp"
-	if err := os.WriteFile(tempPath, []byte(content), 0600); err != nil {
-		return fmt.Errorf("failed to write temp config: %w", err)
-	}
-
-	// Validate SSH config
-	if err := validateSSHConfig(tempPath); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("invalid SSH config: %w", err)
-	}
-
-	// Atomic swap
-	if err := os.Rename(tempPath, configPath); err != nil {
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to update config: %w", err)
-	}
-
-	return nil
+		return filtered
+	})
 }
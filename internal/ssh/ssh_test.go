@@ -0,0 +1,274 @@
+package ssh
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManagedBlockRoundTrip(t *testing.T) {
+	identities := []SSHIdentity{
+		{
+			HostAlias: "github.com-work",
+			HostName:  "github.com",
+			KeyPath:   "/home/test/.ssh/gitx_work",
+		},
+		{
+			HostAlias:     "github.com-personal",
+			HostName:      "gitlab.com",
+			KeyPath:       "/home/test/.ssh/gitx_personal",
+			IdentityAgent: "/run/user/1000/gitx/agents/personal.sock",
+			Options:       []string{"Port 443", "ProxyJump bastion"},
+		},
+	}
+
+	content := "Host example.com\n  User me\n\n" + buildManagedBlockFromIdentities(identities)
+
+	parsed := parseManagedBlock(content)
+	if len(parsed) != 2 {
+		t.Fatalf("Expected 2 identities, got %d", len(parsed))
+	}
+	for i := range identities {
+		if !reflect.DeepEqual(parsed[i], identities[i]) {
+			t.Errorf("Expected %+v, got %+v", identities[i], parsed[i])
+		}
+	}
+
+	if !strings.Contains(content, "  IdentityAgent /run/user/1000/gitx/agents/personal.sock\n") {
+		t.Error("Expected IdentityAgent line in managed block")
+	}
+
+	stripped := removeManagedBlock(content)
+	if strings.Contains(stripped, "github.com-work") || !strings.Contains(stripped, "Host example.com") {
+		t.Errorf("Unexpected content after removing managed block: %q", stripped)
+	}
+}
+
+func TestParseHostEntries(t *testing.T) {
+	content := `Host *
+  AddKeysToAgent yes
+
+Host github-work
+  HostName github.com
+  User git
+  Port 443
+  IdentityFile ~/.ssh/id_work
+  IdentityFile ~/.ssh/id_fallback
+
+Host gitlab personal-gitlab
+  HostName gitlab.com
+
+Host=build-box
+  HostName=10.0.0.5
+  IdentityFile=~/.ssh/id_build
+
+# BEGIN gitx managed
+Host github.com-personal
+  HostName github.com
+  IdentityFile /home/test/.ssh/gitx_personal
+# END gitx managed
+`
+
+	entries := ParseHostEntries(content)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 host entries, got %d: %+v", len(entries), entries)
+	}
+
+	work := entries[0]
+	if work.Host != "github-work" || work.IdentityFile != "~/.ssh/id_work" || !work.IsGitHost() {
+		t.Errorf("Unexpected work entry: %+v", work)
+	}
+	if unmovable := work.UnmovableOptions(); len(unmovable) != 1 || unmovable[0] != "IdentityFile ~/.ssh/id_fallback" {
+		t.Errorf("Unexpected unmovable options: %v", unmovable)
+	}
+
+	if len(work.Options) != 2 || work.Options[0] != "Port 443" {
+		t.Errorf("Unexpected work options: %v", work.Options)
+	}
+
+	build := entries[1]
+	if build.Host != "build-box" || build.HostName != "10.0.0.5" || build.IsGitHost() {
+		t.Errorf("Unexpected build entry: %+v", build)
+	}
+}
+
+func TestInspectKey(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	tmpDir := t.TempDir()
+
+	plain := filepath.Join(tmpDir, "plain")
+	if err := exec.Command("ssh-keygen", "-t", "ed25519", "-f", plain, "-N", "", "-C", "test key", "-q").Run(); err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	protected := filepath.Join(tmpDir, "protected")
+	if err := exec.Command("ssh-keygen", "-t", "ed25519", "-f", protected, "-N", "secret-passphrase", "-q").Run(); err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	info, err := InspectKey(plain)
+	if err != nil {
+		t.Fatalf("Failed to inspect key: %v", err)
+	}
+	if info.Type != "ED25519" || info.Bits != 256 || info.Comment != "test key" {
+		t.Errorf("Unexpected key info: %+v", info)
+	}
+	if info.PassphraseProtected || info.PubMatches == nil || !*info.PubMatches {
+		t.Errorf("Expected unprotected key with matching .pub: %+v", info)
+	}
+	fingerprint, err := KeyFingerprint(plain)
+	if err != nil || fingerprint != info.Fingerprint {
+		t.Errorf("Expected fingerprint %s, got %s (%v)", info.Fingerprint, fingerprint, err)
+	}
+
+	// Swap in the other key's .pub and loosen permissions
+	pub, _ := os.ReadFile(protected + ".pub")
+	os.WriteFile(plain+".pub", pub, 0644)
+	os.Chmod(plain, 0644)
+
+	info, err = InspectKey(plain)
+	if err != nil {
+		t.Fatalf("Failed to inspect key: %v", err)
+	}
+	if !info.LoosePermissions() || info.PubMatches == nil || *info.PubMatches {
+		t.Errorf("Expected loose permissions and mismatched .pub: %+v", info)
+	}
+
+	info, err = InspectKey(protected)
+	if err != nil {
+		t.Fatalf("Failed to inspect key: %v", err)
+	}
+	if !info.PassphraseProtected {
+		t.Error("Expected passphrase-protected key")
+	}
+}
+
+func TestParseGreeting(t *testing.T) {
+	tests := []struct {
+		output string
+		user   string
+		ok     bool
+	}{
+		{"Hi octocat! You've successfully authenticated, but GitHub does not provide shell access.", "octocat", true},
+		{"Welcome to GitLab, @jane.doe!", "jane.doe", true},
+		{"git@github.com: Permission denied (publickey).", "", false},
+	}
+
+	for _, tt := range tests {
+		user, ok := ParseGreeting(tt.output)
+		if user != tt.user || ok != tt.ok {
+			t.Errorf("ParseGreeting(%q) = %q, %v; want %q, %v", tt.output, user, ok, tt.user, tt.ok)
+		}
+	}
+}
+
+func TestConnectionCommand(t *testing.T) {
+	defer func(previous string) { ConfigFile = previous }(ConfigFile)
+
+	ConfigFile = ""
+	if got := strings.Join(connectionCommand("", "github.com-work"), " "); got != "ssh -T -o BatchMode=yes -o ConnectTimeout=10 git@github.com-work" {
+		t.Errorf("default command = %q", got)
+	}
+
+	ConfigFile = "/tmp/gitx/ssh_config"
+	if got := strings.Join(connectionCommand("ssh -v", "github.com-work"), " "); !strings.HasPrefix(got, "ssh -v -F /tmp/gitx/ssh_config -T ") {
+		t.Errorf("relocated config not passed: %q", got)
+	}
+	if got := strings.Join(connectionCommand("ssh -F /etc/other", "github.com-work"), " "); strings.Contains(got, "/tmp/gitx/ssh_config") {
+		t.Errorf("explicit -F overridden: %q", got)
+	}
+}
+
+func TestKnownHostsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := WriteKnownHosts(path, DefaultPinnedHostKeys); err != nil {
+		t.Fatalf("Failed to write known_hosts: %v", err)
+	}
+
+	keys, err := ReadKnownHosts(path)
+	if err != nil {
+		t.Fatalf("Failed to read known_hosts: %v", err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("Expected 3 keys, got %d", len(keys))
+	}
+
+	// GitHub's published fingerprints
+	expected, err := ParseFingerprintList(`# GitHub
+SHA256:+DiY3wvvV6TuJJhbpZisF/zLDA0zPMSvHdkr4UvCOqU
+github.com SHA256:p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM
+3072 SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s github.com (RSA)
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse fingerprint list: %v", err)
+	}
+	for _, key := range keys {
+		if !FingerprintAllowed(expected, key.Host, key.Fingerprint) {
+			t.Errorf("Fingerprint %s of %s not accepted", key.Fingerprint, key.Host)
+		}
+	}
+	if FingerprintAllowed(expected, "gitlab.com", "SHA256:p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM") {
+		t.Error("Host-specific fingerprint should not be accepted for another host")
+	}
+}
+
+func TestParseCertificateListing(t *testing.T) {
+	listing := `/home/test/.ssh/gitx_work-cert.pub:
+        Type: ssh-ed25519-cert-v01@openssh.com user certificate
+        Public key: ED25519-CERT SHA256:gv517hACYaYn5B/GrkZeTeHNtJ4/SuyDRgEJHFjZuic
+        Signing CA: ED25519 SHA256:s8ur6UEJDIWjDkgsJ7Vh6PvUGStWgFGpUPj9CJ0IeqM (using ssh-ed25519)
+        Key ID: "gitx-work"
+        Serial: 7
+        Valid: from 2026-10-18T22:08:00 to 2026-10-20T22:09:23
+        Principals: 
+                git
+                alice
+        Critical Options: (none)
+        Extensions: 
+                permit-pty
+`
+
+	cert, err := parseCertificateListing(listing)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	if cert.KeyID != "gitx-work" || cert.Serial != "7" {
+		t.Errorf("Unexpected certificate: %+v", cert)
+	}
+	if strings.Join(cert.Principals, ",") != "git,alice" {
+		t.Errorf("Expected principals git,alice, got %v", cert.Principals)
+	}
+
+	validBefore := time.Date(2026, 10, 20, 22, 9, 23, 0, time.Local)
+	if !cert.ValidBefore.Equal(validBefore) {
+		t.Errorf("Expected valid before %v, got %v", validBefore, cert.ValidBefore)
+	}
+	if !cert.ExpiresWithin(validBefore.Add(-24*time.Hour), 48*time.Hour) || cert.Expired(validBefore.Add(-time.Second)) {
+		t.Error("Unexpected expiry evaluation")
+	}
+
+	forever, err := parseCertificateListing("        Valid: forever\n        Principals: (none)\n")
+	if err != nil || !forever.ValidBefore.IsZero() || forever.ExpiresWithin(time.Now(), 1000*time.Hour) {
+		t.Errorf("Expected certificate valid forever: %+v (%v)", forever, err)
+	}
+}
+
+func TestRotatedKeyPath(t *testing.T) {
+	defer func(previous string) { KeyDir = previous }(KeyDir)
+	KeyDir = t.TempDir()
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	path, err := RotatedKeyPath("work", now)
+	if err != nil || path != filepath.Join(KeyDir, "gitx_work_20260301") {
+		t.Fatalf("RotatedKeyPath = %q, %v", path, err)
+	}
+	os.WriteFile(path, []byte("key"), 0600)
+	if path, _ := RotatedKeyPath("work", now); path != filepath.Join(KeyDir, "gitx_work_20260301-093000") {
+		t.Errorf("taken path not avoided: %q", path)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	keyringCmd.AddCommand(keyringBackendsCmd)
+	keyringCmd.AddCommand(keyringUseCmd)
+	rootCmd.AddCommand(keyringCmd)
+}
+
+var keyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Choose where gitx stores secrets",
+	Long: `gitx stores PATs and other secrets in a system keyring. By default it uses the first
+available backend and falls back to an encrypted file in the gitx config directory,
+unlocked with $` + keychain.PasswordEnv + ` or a passphrase prompt.
+The backend can be set in the config file ('gitx keyring use'), with
+$GITX_KEYRING_BACKEND, or per command with --keyring-backend.`,
+}
+
+var keyringBackendsCmd = &cobra.Command{
+	Use:   "backends",
+	Short: "List keyring backends available on this system",
+	Run: func(cmd *cobra.Command, args []string) {
+		listKeyringBackends()
+	},
+}
+
+var keyringUseCmd = &cobra.Command{
+	Use:   "use [backend]",
+	Short: "Set the keyring backend in the gitx config ('auto' to clear)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := useKeyringBackend(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func listKeyringBackends() {
+	active, err := keychain.ActiveBackend()
+	for _, name := range keychain.AvailableBackends() {
+		if name == active {
+			fmt.Printf("%s %s %s\n", ui.SuccessText.Render("●"), name, ui.MutedText.Render("(active)"))
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if err != nil {
+		fmt.Println(ui.WarningText.Render("⚠️  No usable keyring: " + err.Error()))
+	}
+}
+
+func useKeyringBackend(name string) error {
+	if name == "auto" {
+		name = ""
+	} else if !keychain.ValidBackend(name) {
+		return fmt.Errorf("unknown keyring backend %q (valid: %s)", name, strings.Join(keychain.Backends(), ", "))
+	}
+
+	if err := config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.KeyringBackend = name
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if name == "" {
+		fmt.Println(ui.SuccessText.Render("✓ Keyring backend: auto"))
+		return nil
+	}
+	keychain.Backend = name
+	if _, err := keychain.ActiveBackend(); err != nil {
+		fmt.Println(ui.WarningText.Render("⚠️  Saved, but the backend can't be opened here: " + err.Error()))
+		return nil
+	}
+	fmt.Println(ui.SuccessText.Render("✓ Keyring backend: " + name))
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var keysFormat string
+
+func init() {
+	keysCmd.Flags().StringVar(&keysFormat, "format", "table", "Output format: table or json")
+	rootCmd.AddCommand(keysCmd)
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Audit the SSH keys of all identities",
+	Long: `Report type, size, fingerprint, age and permissions of every identity's SSH key,
+and flag loose permissions, mismatched .pub files, weak RSA keys and keys
+shared between identities.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listKeys(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// keyReport is the per-identity result of the key audit
+type keyReport struct {
+	Alias               string      `json:"alias"`
+	Path                string      `json:"path"`
+	Type                string      `json:"type,omitempty"`
+	Bits                int         `json:"bits,omitempty"`
+	Fingerprint         string      `json:"fingerprint,omitempty"`
+	Comment             string      `json:"comment,omitempty"`
+	Modified            time.Time   `json:"modified"`
+	AgeDays             int         `json:"age_days"`
+	Permissions         string      `json:"permissions,omitempty"`
+	PubMatches          *bool       `json:"pub_matches"`
+	PassphraseProtected bool        `json:"passphrase_protected"`
+	Certificate         *certReport `json:"certificate,omitempty"`
+	SharedWith          []string    `json:"shared_with,omitempty"`
+	Issues              []string    `json:"issues,omitempty"`
+}
+
+// certReport summarises an identity's SSH certificate
+type certReport struct {
+	Path        string     `json:"path"`
+	Principals  []string   `json:"principals,omitempty"`
+	ValidBefore *time.Time `json:"valid_before,omitempty"`
+}
+
+func buildKeyReports(identities []config.Identity) []keyReport {
+	var reports []keyReport
+	byFingerprint := map[string][]string{}
+
+	for _, id := range identities {
+		if id.SSHKeyPath == "" {
+			continue
+		}
+		report := keyReport{Alias: id.Alias, Path: id.SSHKeyPath}
+
+		info, err := ssh.InspectKey(id.SSHKeyPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				report.Issues = append(report.Issues, "key file is missing")
+			} else {
+				report.Issues = append(report.Issues, err.Error())
+			}
+			reports = append(reports, report)
+			continue
+		}
+
+		report.Type = info.Type
+		report.Bits = info.Bits
+		report.Fingerprint = info.Fingerprint
+		report.Comment = info.Comment
+		report.Modified = info.ModTime
+		report.AgeDays = int(time.Since(info.ModTime).Hours() / 24)
+		report.Permissions = fmt.Sprintf("%04o", uint32(info.Mode.Perm()))
+		report.PubMatches = info.PubMatches
+		report.PassphraseProtected = info.PassphraseProtected
+
+		if info.LoosePermissions() {
+			report.Issues = append(report.Issues, fmt.Sprintf("permissions %s are looser than 0600", report.Permissions))
+		}
+		if info.PubMatches != nil && !*info.PubMatches {
+			report.Issues = append(report.Issues, ".pub is missing or does not match the private key")
+		}
+		if info.Type == "RSA" && info.Bits < 3072 {
+			report.Issues = append(report.Issues, fmt.Sprintf("RSA key is only %d bits", info.Bits))
+		}
+
+		if id.SSHCertPath != "" {
+			report.Certificate = &certReport{Path: id.SSHCertPath}
+			if cert, err := ssh.InspectCertificate(id.SSHCertPath); err == nil {
+				report.Certificate.Principals = cert.Principals
+				if !cert.ValidBefore.IsZero() {
+					report.Certificate.ValidBefore = &cert.ValidBefore
+				}
+			}
+			if warning := certificateWarning(&id); warning != "" {
+				report.Issues = append(report.Issues, warning)
+			}
+		}
+
+		byFingerprint[info.Fingerprint] = append(byFingerprint[info.Fingerprint], id.Alias)
+		reports = append(reports, report)
+	}
+
+	// Flag keys reused by more than one identity
+	for i := range reports {
+		for _, alias := range byFingerprint[reports[i].Fingerprint] {
+			if reports[i].Fingerprint != "" && alias != reports[i].Alias {
+				reports[i].SharedWith = append(reports[i].SharedWith, alias)
+			}
+		}
+		if len(reports[i].SharedWith) > 0 {
+			reports[i].Issues = append(reports[i].Issues, "same key used by "+strings.Join(reports[i].SharedWith, ", "))
+		}
+	}
+
+	return reports
+}
+
+func listKeys() error {
+	if keysFormat != "table" && keysFormat != "json" {
+		return fmt.Errorf("unknown format '%s' (use table or json)", keysFormat)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	reports := buildKeyReports(cfg.Identities)
+
+	if keysFormat == "json" {
+		if reports == nil {
+			reports = []keyReport{}
+		}
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(reports) == 0 {
+		fmt.Println(ui.WarningBox.Render("⚠️  No identities with SSH keys configured."))
+		return nil
+	}
+
+	var rows []string
+	header := fmt.Sprintf("%-12s %-8s %5s  %-50s %6s  %-5s %-4s %-10s",
+		"Alias", "Type", "Bits", "Fingerprint", "Age", "Perms", "Pub", "Passphrase")
+	rows = append(rows, ui.TableHeaderStyle.Render(header))
+	rows = append(rows, strings.Repeat("─", lipgloss.Width(header)+2))
+
+	for _, r := range reports {
+		pub := "?"
+		if r.PubMatches != nil {
+			pub = "ok"
+			if !*r.PubMatches {
+				pub = "bad"
+			}
+		}
+		passphrase := "no"
+		if r.PassphraseProtected {
+			passphrase = "yes"
+		}
+		row := fmt.Sprintf("%-12s %-8s %5d  %-50s %5dd  %-5s %-4s %-10s",
+			r.Alias, r.Type, r.Bits, r.Fingerprint, r.AgeDays, r.Permissions, pub, passphrase)
+		rows = append(rows, ui.TableRowStyle.Render(row))
+		for _, issue := range r.Issues {
+			rows = append(rows, ui.TableRowStyle.Render(ui.WarningText.Render("   ⚠️  "+issue)))
+		}
+	}
+
+	fmt.Println(ui.BoxStyle.Render("🔑 Identity SSH Keys\n\n" + strings.Join(rows, "\n")))
+	return nil
+}
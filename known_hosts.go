@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	knownHostsFingerprints string
+	knownHostsScan         bool
+)
+
+func init() {
+	knownHostsVerifyCmd.Flags().StringVar(&knownHostsFingerprints, "fingerprints", "", "File with expected SHA256 fingerprints (required)")
+	knownHostsUpdateCmd.Flags().StringVar(&knownHostsFingerprints, "fingerprints", "", "File with expected SHA256 fingerprints (required with --scan)")
+	knownHostsUpdateCmd.Flags().BoolVar(&knownHostsScan, "scan", false, "Fetch current host keys with ssh-keyscan and pin those matching --fingerprints")
+	knownHostsCmd.AddCommand(knownHostsVerifyCmd)
+	knownHostsCmd.AddCommand(knownHostsUpdateCmd)
+	rootCmd.AddCommand(knownHostsCmd)
+}
+
+var knownHostsCmd = &cobra.Command{
+	Use:   "known-hosts",
+	Short: "Manage the gitx known_hosts file",
+	Long: `gitx keeps its own known_hosts file, seeded from pinned host keys (GitHub's published
+keys by default), and points every managed SSH host entry at it with HostKeyAlias
+so host aliases like github.com-work never trigger first-connection prompts.`,
+}
+
+var knownHostsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Compare the gitx known_hosts file against a fingerprint list",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := verifyKnownHosts(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var knownHostsUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Rewrite the gitx known_hosts file from pinned host keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := updateKnownHosts(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// pinnedHostKeys returns the configured pinned host keys, or the defaults
+func pinnedHostKeys(cfg *config.Config) map[string][]string {
+	if len(cfg.PinnedHostKeys) > 0 {
+		return cfg.PinnedHostKeys
+	}
+	return ssh.DefaultPinnedHostKeys
+}
+
+// ensureKnownHosts seeds the gitx known_hosts file from the pinned keys if it doesn't exist yet
+func ensureKnownHosts() error {
+	if ssh.KnownHostsFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(ssh.KnownHostsFile); err == nil {
+		return nil
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	return ssh.WriteKnownHosts(ssh.KnownHostsFile, pinnedHostKeys(cfg))
+}
+
+func loadFingerprintList() (map[string][]string, error) {
+	if knownHostsFingerprints == "" {
+		return nil, fmt.Errorf("--fingerprints is required")
+	}
+	data, err := os.ReadFile(knownHostsFingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprint list: %w", err)
+	}
+	expected, err := ssh.ParseFingerprintList(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(expected) == 0 {
+		return nil, fmt.Errorf("fingerprint list %s is empty", knownHostsFingerprints)
+	}
+	return expected, nil
+}
+
+func verifyKnownHosts() error {
+	expected, err := loadFingerprintList()
+	if err != nil {
+		return err
+	}
+	if err := ensureKnownHosts(); err != nil {
+		return err
+	}
+
+	keys, err := ssh.ReadKnownHosts(ssh.KnownHostsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ssh.KnownHostsFile, err)
+	}
+
+	mismatches := 0
+	for _, key := range keys {
+		if ssh.FingerprintAllowed(expected, key.Host, key.Fingerprint) {
+			fmt.Printf("%s %s %s\n", ui.SuccessText.Render("✓"), key.Host, key.Fingerprint)
+		} else {
+			fmt.Printf("%s %s %s %s\n", ui.ErrorText.Render("✗"), key.Host, key.Fingerprint, ui.ErrorText.Render("(not in fingerprint list)"))
+			mismatches++
+		}
+	}
+
+	// Expected fingerprints for a specific host that aren't pinned
+	hosts := make([]string, 0, len(expected))
+	for host := range expected {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		for _, fp := range expected[host] {
+			found := false
+			for _, key := range keys {
+				if key.Host == host && key.Fingerprint == fp {
+					found = true
+					break
+				}
+			}
+			if !found {
+				fmt.Printf("%s %s %s %s\n", ui.WarningText.Render("?"), host, fp, ui.WarningText.Render("(expected but not pinned)"))
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d host key(s) in %s do not match the fingerprint list", mismatches, ssh.KnownHostsFile)
+	}
+	fmt.Println(ui.SuccessText.Render("✓ All pinned host keys match"))
+	return nil
+}
+
+func updateKnownHosts() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	pinned := pinnedHostKeys(cfg)
+
+	if knownHostsScan {
+		expected, err := loadFingerprintList()
+		if err != nil {
+			return err
+		}
+
+		// Only keys whose fingerprint was provided out of band are trusted
+		scanned := map[string][]string{}
+		for host := range pinned {
+			keys, err := ssh.ScanHostKeys(host)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if ssh.FingerprintAllowed(expected, host, key.Fingerprint) {
+					scanned[host] = append(scanned[host], key.Key)
+				} else {
+					fmt.Printf("%s %s %s %s\n", ui.WarningText.Render("!"), host, key.Fingerprint, ui.WarningText.Render("(skipped: not in fingerprint list)"))
+				}
+			}
+			if len(scanned[host]) == 0 {
+				return fmt.Errorf("none of the keys offered by %s match the fingerprint list", host)
+			}
+		}
+
+		if err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.PinnedHostKeys = scanned
+			return nil
+		}); err != nil {
+			return err
+		}
+		pinned = scanned
+	}
+
+	if err := ssh.WriteKnownHosts(ssh.KnownHostsFile, pinned); err != nil {
+		return err
+	}
+	// Bring existing entries up to date with HostKeyAlias/UserKnownHostsFile
+	if err := ssh.RefreshManagedBlock(); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+
+	fmt.Println(ui.SuccessText.Render("✓ Known hosts updated: " + ssh.KnownHostsFile))
+	return nil
+}
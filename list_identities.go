@@ -62,6 +62,12 @@ func listIdentities() error {
 		Render("🔐 Configured Identities\n\n" + content)
 
 	fmt.Println(box)
+
+	for i := range cfg.Identities {
+		if warning := patExpiryWarning(cfg, &cfg.Identities[i]); warning != "" {
+			fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  %s: %s", cfg.Identities[i].Alias, warning)))
+		}
+	}
 	return nil
 }
 
@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
 	"github.com/csawai/git-identity-switcher/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -20,11 +24,24 @@ var (
 	buildDate = "unknown"
 )
 
+// keyringBackendFlag overrides the keyring backend from the config file and $GITX_KEYRING_BACKEND
+var keyringBackendFlag string
+
+// Location overrides; each flag beats its environment variable
+var (
+	configDirFlag string // $GITX_CONFIG_DIR
+	sshConfigFlag string // $GITX_SSH_CONFIG
+	sshDirFlag    string // $GITX_SSH_DIR
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "gitx",
 	Short: "Git Identity Switcher - Manage multiple GitHub identities safely",
 	Long: `gitx is a CLI tool for managing multiple GitHub identities with per-repo binding.
 It helps developers safely switch between work, personal, and client accounts.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		configurePaths()
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Show banner and help if no command provided
 		showBanner()
@@ -45,6 +62,10 @@ Built:   %s`, version, commit, buildDate)
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&configDirFlag, "config", "", "Config directory (default $GITX_CONFIG_DIR, $XDG_CONFIG_HOME/gitx or ~/.config/gitx)")
+	rootCmd.PersistentFlags().StringVar(&sshConfigFlag, "ssh-config", "", "SSH config file to manage (default $GITX_SSH_CONFIG or ~/.ssh/config)")
+	rootCmd.PersistentFlags().StringVar(&sshDirFlag, "ssh-dir", "", "Directory for generated SSH keys (default $GITX_SSH_DIR or ~/.ssh)")
+	rootCmd.PersistentFlags().StringVar(&keyringBackendFlag, "keyring-backend", "", "Keyring backend for secrets (secret-service, kwallet, pass, keyctl, keychain, wincred, file)")
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(addIdentityCmd)
@@ -55,6 +76,46 @@ func init() {
 	// show_key.go registers showKeyCmd and copyKeyCmd
 }
 
+// configurePaths points the internal packages at gitx-managed files
+func configurePaths() {
+	config.ConfigDirOverride = configDirFlag
+	ssh.ConfigFile = pathOverride(sshConfigFlag, "GITX_SSH_CONFIG")
+	ssh.KeyDir = pathOverride(sshDirFlag, "GITX_SSH_DIR")
+
+	if path, err := config.GetKnownHostsPath(); err == nil {
+		ssh.KnownHostsFile = path
+	}
+	if dir, err := config.GetKeyringDir(); err == nil {
+		keychain.FileDir = dir
+	}
+
+	// --keyring-backend beats $GITX_KEYRING_BACKEND beats the config file
+	keychain.Backend = keyringBackendFlag
+	if keychain.Backend == "" {
+		keychain.Backend = os.Getenv("GITX_KEYRING_BACKEND")
+	}
+	if keychain.Backend == "" {
+		if cfg, err := config.LoadConfig(); err == nil {
+			keychain.Backend = cfg.KeyringBackend
+		}
+	}
+}
+
+// pathOverride returns flag, else the value of env, as an absolute path
+func pathOverride(flag, env string) string {
+	path := flag
+	if path == "" {
+		path = os.Getenv(env)
+	}
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		// Use styled error box
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+const patDateLayout = "2006-01-02"
+
+var (
+	patExpires string
+	patScopes  []string
+	patNote    string
+)
+
+func init() {
+	addPATInfoFlags(patSetCmd)
+	addPATInfoFlags(patRotateCmd)
+	patCmd.AddCommand(patSetCmd)
+	patCmd.AddCommand(patRotateCmd)
+	rootCmd.AddCommand(patCmd)
+}
+
+var patCmd = &cobra.Command{
+	Use:   "pat",
+	Short: "Manage personal access tokens of PAT identities",
+}
+
+var patSetCmd = &cobra.Command{
+	Use:   "set [alias]",
+	Short: "Record a PAT's expiry date, scopes and note",
+	Long: `Record metadata about an identity's personal access token. Without flags, gitx
+prompts for each field. status and list identities warn when the token is about to
+expire (pat_expiry_warning_days in identities.json, 14 by default).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setPATInfo(cmd, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var patRotateCmd = &cobra.Command{
+	Use:   "rotate [alias]",
+	Short: "Replace an identity's PAT and clear stale credential helper entries",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rotatePAT(cmd, args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// addPATInfoFlags registers the flags read by patInfoFromInput on cmd
+func addPATInfoFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&patExpires, "expires", "", "Token expiry date (YYYY-MM-DD, or 'never')")
+	cmd.Flags().StringSliceVar(&patScopes, "scopes", nil, "Token scopes, comma-separated")
+	cmd.Flags().StringVar(&patNote, "note", "", "Free-form note about the token")
+}
+
+func patIdentity(alias string) (*config.Identity, error) {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+	if identity.AuthMethod != "pat" {
+		return nil, fmt.Errorf("identity '%s' does not use a PAT", alias)
+	}
+	return identity, nil
+}
+
+func setPATInfo(cmd *cobra.Command, alias string) error {
+	identity, err := patIdentity(alias)
+	if err != nil {
+		return err
+	}
+	info, err := patInfoFromInput(cmd, bufio.NewReader(os.Stdin), identity.PAT)
+	if err != nil {
+		return err
+	}
+	identity.PAT = info
+	if err := config.UpdateIdentity(*identity); err != nil {
+		return err
+	}
+	fmt.Println(ui.SuccessText.Render("✓ PAT details saved for '" + alias + "'"))
+	fmt.Println(describePAT(info))
+	return nil
+}
+
+func rotatePAT(cmd *cobra.Command, alias string) error {
+	identity, err := patIdentity(alias)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(os.Stdin)
+	token, err := readPAT(reader)
+	if err != nil {
+		return err
+	}
+
+	// The old expiry belongs to the old token, so only keep scopes and note as
+	// defaults. The details are checked before the token replaces the old one.
+	var previous *config.PATInfo
+	if identity.PAT != nil {
+		previous = &config.PATInfo{Scopes: identity.PAT.Scopes, Note: identity.PAT.Note}
+	}
+	info, err := patInfoFromInput(cmd, reader, previous)
+	if err != nil {
+		return err
+	}
+	if err := secretStore.Store(alias, "pat", token); err != nil {
+		return fmt.Errorf("failed to store PAT: %w", err)
+	}
+	fmt.Println(ui.SuccessText.Render("✓ New PAT stored in keychain"))
+	identity.PAT = info
+	if err := config.UpdateIdentity(*identity); err != nil {
+		return err
+	}
+
+	// Helpers such as store or osxkeychain still hold the old token and would keep offering it
+	if identity.GitHubUser != "" {
+		reportCredentialCleanup(keychain.RemoveGitCredentials(identity.GitHubUser, credentialHosts))
+	}
+	fmt.Println(describePAT(info))
+	return nil
+}
+
+// credentialHosts are the hosts git may hold HTTPS credentials for on behalf of
+// a PAT identity. Identities are github.com accounts, so this is the same for all.
+var credentialHosts = []string{"github.com"}
+
+// reportCredentialCleanup prints which helpers were asked to erase credentials
+// and which stored entries were removed
+func reportCredentialCleanup(cleanup *keychain.CredentialCleanup, err error) {
+	if cleanup != nil {
+		if len(cleanup.Helpers) > 0 {
+			fmt.Println(ui.MutedText.Render("  Credential helpers: " + strings.Join(cleanup.Helpers, ", ")))
+		}
+		for _, entry := range cleanup.Removed {
+			fmt.Printf("  %s %s %s\n", ui.SuccessText.Render("✓ Removed"), entry, ui.MutedText.Render("("+entry.File+")"))
+		}
+		if len(cleanup.Removed) == 0 {
+			fmt.Println(ui.MutedText.Render("  No stored credential entries found"))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to remove git credentials: "+err.Error()))
+	}
+}
+
+// patInfoFromInput builds PAT metadata from --expires/--scopes/--note, or by
+// prompting when none of them were given
+func patInfoFromInput(cmd *cobra.Command, reader *bufio.Reader, current *config.PATInfo) (*config.PATInfo, error) {
+	info := &config.PATInfo{}
+	if current != nil {
+		*info = *current
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("expires") || flags.Changed("scopes") || flags.Changed("note") {
+		if flags.Changed("expires") {
+			expiry, err := parsePATExpiry(patExpires)
+			if err != nil {
+				return nil, err
+			}
+			info.ExpiresAt = expiry
+		}
+		if flags.Changed("scopes") {
+			info.Scopes = patScopes
+		}
+		if flags.Changed("note") {
+			info.Note = patNote
+		}
+		return info, nil
+	}
+	return promptPATInfo(reader, info)
+}
+
+// promptPATInfo asks for expiry, scopes and note, offering info's values as defaults
+func promptPATInfo(reader *bufio.Reader, info *config.PATInfo) (*config.PATInfo, error) {
+	if info == nil {
+		info = &config.PATInfo{}
+	}
+	prompt := func(label, def string) string {
+		if def != "" {
+			fmt.Printf("%s [%s]: ", ui.InfoText.Render(label), def)
+		} else {
+			fmt.Printf("%s: ", ui.InfoText.Render(label))
+		}
+		value, _ := reader.ReadString('\n')
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return def
+		}
+		return value
+	}
+
+	currentExpiry := ""
+	if info.ExpiresAt != nil {
+		currentExpiry = info.ExpiresAt.Format(patDateLayout)
+	}
+	expiry, err := parsePATExpiry(prompt("📅 Token expiry (YYYY-MM-DD, blank for none)", currentExpiry))
+	if err != nil {
+		return nil, err
+	}
+	info.ExpiresAt = expiry
+
+	scopes := prompt("🔑 Scopes (comma-separated)", strings.Join(info.Scopes, ","))
+	info.Scopes = nil
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			info.Scopes = append(info.Scopes, scope)
+		}
+	}
+	info.Note = prompt("🗒️  Note", info.Note)
+	return info, nil
+}
+
+// parsePATExpiry parses a YYYY-MM-DD expiry date; "" and "never" mean no expiry
+func parsePATExpiry(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.EqualFold(value, "never") {
+		return nil, nil
+	}
+	expiry, err := time.ParseInLocation(patDateLayout, value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiry date %q (want YYYY-MM-DD)", value)
+	}
+	return &expiry, nil
+}
+
+func describePAT(info *config.PATInfo) string {
+	expiry := "never"
+	if info.ExpiresAt != nil {
+		expiry = info.ExpiresAt.Format(patDateLayout)
+	}
+	scopes := "(unknown)"
+	if len(info.Scopes) > 0 {
+		scopes = strings.Join(info.Scopes, ", ")
+	}
+	content := fmt.Sprintf("Expires: %s\nScopes:  %s", expiry, scopes)
+	if info.Note != "" {
+		content += "\nNote:    " + info.Note
+	}
+	return ui.InfoBox.Render(content)
+}
+
+// patExpiryWarning returns a message when the identity's PAT has expired or
+// expires within the configured warning window, and "" otherwise
+func patExpiryWarning(cfg *config.Config, identity *config.Identity) string {
+	if identity.AuthMethod != "pat" || identity.PAT == nil || identity.PAT.ExpiresAt == nil {
+		return ""
+	}
+	now := time.Now()
+	expiry := identity.PAT.ExpiresAt.Format(patDateLayout)
+	if identity.PAT.Expired(now) {
+		return fmt.Sprintf("PAT expired on %s (run 'gitx pat rotate %s')", expiry, identity.Alias)
+	}
+	if identity.PAT.ExpiresWithin(now, cfg.PATExpiryWarning()) {
+		return fmt.Sprintf("PAT expires on %s (run 'gitx pat rotate %s')", expiry, identity.Alias)
+	}
+	return ""
+}
@@ -49,12 +49,22 @@ func removeIdentity(alias string) error {
 	var items []string
 	items = append(items, "• Identity config entry")
 	
-	if identity.SSHHostAlias != "" {
+	if identity.SSHHostAlias != "" && !identity.ExternalHostEntry {
 		items = append(items, fmt.Sprintf("• SSH config entry: %s", identity.SSHHostAlias))
 	}
 	
+	secrets, _ := secretStore.List(alias)
+	if identity.AuthMethod == "pat" || len(secrets) > 0 {
+		var keys []string
+		for _, record := range secrets {
+			keys = append(keys, record.Key)
+		}
+		if len(keys) == 0 {
+			keys = []string{"pat"}
+		}
+		items = append(items, fmt.Sprintf("• Keychain secrets: %s", strings.Join(keys, ", ")))
+	}
 	if identity.AuthMethod == "pat" {
-		items = append(items, "• Keychain secrets (PAT)")
 		items = append(items, "• Git credential helper entries")
 	}
 	
@@ -104,7 +114,7 @@ func removeIdentity(alias string) error {
 	}
 
 		// Remove SSH config entry
-	if identity.SSHHostAlias != "" {
+	if identity.SSHHostAlias != "" && !identity.ExternalHostEntry {
 		if err := ui.SpinnerWithFunc("Removing SSH config entry", func() error {
 			return ssh.RemoveSSHConfigEntry(identity.SSHHostAlias)
 		}); err != nil {
@@ -112,22 +122,35 @@ func removeIdentity(alias string) error {
 		}
 	}
 
-	// Remove keychain secrets and git credentials
-	if identity.AuthMethod == "pat" {
-		// Remove from gitx keychain
+	// Stop the identity's dedicated agent
+	if identity.IsolatedAgent {
+		if socketPath, err := identityAgentSocket(alias); err == nil {
+			if err := ssh.StopAgent(socketPath); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to stop isolated agent: "+err.Error()))
+			}
+		}
+	}
+
+	// Remove keychain secrets
+	if identity.AuthMethod == "pat" || len(secrets) > 0 {
 		if err := ui.SpinnerWithFunc("Removing keychain secrets", func() error {
-			return keychain.DeleteAllSecrets(alias)
+			return secretStore.DeleteAll(alias)
 		}); err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to remove keychain secrets: "+err.Error()))
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: "+err.Error()))
 		}
-		
+	}
+
+	// Remove git credentials
+	if identity.AuthMethod == "pat" {
 		// Remove from git credential helper (osxkeychain, credential store, etc.)
 		if identity.GitHubUser != "" {
-			if err := ui.SpinnerWithFunc("Removing git credentials", func() error {
-				return keychain.RemoveGitCredentials(identity.GitHubUser)
-			}); err != nil {
-				fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to remove git credentials: "+err.Error()))
-			}
+			var cleanup *keychain.CredentialCleanup
+			err := ui.SpinnerWithFunc("Removing git credentials", func() error {
+				var err error
+				cleanup, err = keychain.RemoveGitCredentials(identity.GitHubUser, credentialHosts)
+				return err
+			})
+			reportCredentialCleanup(cleanup, err)
 		}
 	}
 
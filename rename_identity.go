@@ -0,0 +1,364 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var renameDryRun bool
+
+func init() {
+	renameIdentityCmd.Flags().BoolVar(&renameDryRun, "dry-run", false, "Show what would be changed without making changes")
+	rootCmd.AddCommand(renameIdentityCmd)
+}
+
+var renameIdentityCmd = &cobra.Command{
+	Use:   "rename identity [old] [new]",
+	Short: "Rename an identity and everything named after it",
+	Long: `Rename an identity's alias along with the names derived from it: the gitx_<alias>
+key files, the github.com-<alias> host entry in the managed SSH config block, the
+identity's keychain secrets, and the gitx.bound marker and remote URL of every
+repository bound to it. If any step fails, the steps already done are undone.`,
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		args = identityArgs(args)
+		err := fmt.Errorf("expected an old and a new alias")
+		if len(args) == 2 {
+			err = renameIdentity(args[0], args[1])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// renameStep is one reversible part of a rename
+type renameStep struct {
+	desc string
+	do   func() error
+	undo func() error
+}
+
+func renameIdentity(oldAlias, newAlias string) error {
+	if err := config.ValidateAlias(newAlias); err != nil {
+		return err
+	}
+	if oldAlias == newAlias {
+		return fmt.Errorf("identity is already called '%s'", newAlias)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	var original *config.Identity
+	for i := range cfg.Identities {
+		switch cfg.Identities[i].Alias {
+		case oldAlias:
+			original = &cfg.Identities[i]
+		case newAlias:
+			return fmt.Errorf("identity with alias '%s' already exists", newAlias)
+		}
+	}
+	if original == nil {
+		return fmt.Errorf("identity '%s' not found", oldAlias)
+	}
+
+	plan, err := planRename(cfg, original, newAlias)
+	if err != nil {
+		return err
+	}
+	steps := plan.steps
+
+	if renameDryRun {
+		fmt.Printf("[DRY RUN] Would rename '%s' to '%s':\n", oldAlias, newAlias)
+		for _, step := range steps {
+			fmt.Printf("  %s\n", step.desc)
+		}
+		return nil
+	}
+
+	for i, step := range steps {
+		if err := step.do(); err != nil {
+			fmt.Println(ui.ErrorText.Render("✗ " + step.desc + ": " + err.Error()))
+			rollbackRename(steps[:i])
+			return fmt.Errorf("rename failed; changes were rolled back")
+		}
+		fmt.Println(ui.SuccessText.Render("✓ " + step.desc))
+	}
+
+	// Past the point of no return: drop the secrets under the old alias
+	for _, key := range plan.secretKeys {
+		if err := secretStore.Delete(oldAlias, key); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to delete "+oldAlias+":"+key+" from the keychain: "+err.Error()))
+		}
+	}
+	if renamed := plan.renamed; renamed.IsolatedAgent && renamed.AuthMethod == "ssh" {
+		if socket, err := identityAgentSocket(oldAlias); err == nil {
+			ssh.StopAgent(socket)
+		}
+		if err := ensureIdentityAgent(renamed); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to start isolated agent: "+err.Error()))
+		}
+	}
+
+	fmt.Println(ui.Celebration(fmt.Sprintf("Identity '%s' renamed to '%s'", oldAlias, newAlias)))
+	return nil
+}
+
+// renamePlan is the renamed identity and the steps that get it there
+type renamePlan struct {
+	renamed    *config.Identity
+	steps      []renameStep
+	secretKeys []string // copied to the new alias, deleted from the old one at the end
+}
+
+// planRename works out every change renaming original to newAlias involves
+func planRename(cfg *config.Config, original *config.Identity, newAlias string) (*renamePlan, error) {
+	oldAlias := original.Alias
+	renamed := *original
+	renamed.Alias = newAlias
+	renamed.KeyRotations = append([]config.KeyRotation(nil), original.KeyRotations...)
+	var steps []renameStep
+
+	// Key files named after the alias move with it
+	moves, err := renamedKeyFiles(original, newAlias)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]string, 0, len(moves))
+	for from := range moves {
+		sources = append(sources, from)
+	}
+	sort.Strings(sources)
+	for _, from := range sources {
+		from, to := from, moves[from]
+		steps = append(steps, renameStep{
+			desc: fmt.Sprintf("Rename %s -> %s", from, to),
+			do:   func() error { return os.Rename(from, to) },
+			undo: func() error { return os.Rename(to, from) },
+		})
+	}
+	if to, ok := moves[renamed.SSHKeyPath]; ok {
+		renamed.SSHKeyPath = to
+	}
+	if to, ok := moves[renamed.SSHCertPath]; ok {
+		renamed.SSHCertPath = to
+	}
+	for i := range renamed.KeyRotations {
+		if to, ok := moves[renamed.KeyRotations[i].NewKeyPath]; ok {
+			renamed.KeyRotations[i].NewKeyPath = to
+		}
+	}
+
+	// Managed SSH host entry
+	defaultHost := fmt.Sprintf("github.com-%s", oldAlias)
+	if original.SSHHostAlias == defaultHost && !original.ExternalHostEntry {
+		renamed.SSHHostAlias = fmt.Sprintf("github.com-%s", newAlias)
+		for _, id := range cfg.Identities {
+			if id.SSHHostAlias == renamed.SSHHostAlias {
+				return nil, fmt.Errorf("SSH host %s is already used by '%s'", renamed.SSHHostAlias, id.Alias)
+			}
+		}
+		agentSocket := ""
+		if original.IsolatedAgent {
+			if agentSocket, err = identityAgentSocket(newAlias); err != nil {
+				return nil, err
+			}
+		}
+		var before ssh.SSHIdentity
+		missing := false
+		steps = append(steps, renameStep{
+			desc: fmt.Sprintf("Rename SSH host %s -> %s", original.SSHHostAlias, renamed.SSHHostAlias),
+			do: func() error {
+				err := ssh.RenameSSHConfigEntry(original.SSHHostAlias, renamed.SSHHostAlias, func(entry *ssh.SSHIdentity) {
+					before = *entry
+					entry.KeyPath = renamed.SSHKeyPath
+					if entry.CertificateFile != "" {
+						entry.CertificateFile = renamed.SSHCertPath
+					}
+					if entry.IdentityAgent != "" && agentSocket != "" {
+						entry.IdentityAgent = agentSocket
+					}
+				})
+				// Nothing to rename if the entry was removed by hand; bind recreates it
+				if errors.Is(err, ssh.ErrNoManagedEntry) {
+					missing = true
+					return nil
+				}
+				return err
+			},
+			undo: func() error {
+				if missing {
+					return nil
+				}
+				return ssh.RenameSSHConfigEntry(renamed.SSHHostAlias, original.SSHHostAlias, func(entry *ssh.SSHIdentity) {
+					*entry = before
+				})
+			},
+		})
+	} else if original.SSHKeyPath != renamed.SSHKeyPath && original.SSHHostAlias != "" && !original.ExternalHostEntry {
+		steps = append(steps, renameStep{
+			desc: fmt.Sprintf("Point SSH host %s at %s", original.SSHHostAlias, renamed.SSHKeyPath),
+			do:   func() error { return ssh.AddSSHConfigEntry(original.SSHHostAlias, renamed.SSHKeyPath) },
+			undo: func() error { return ssh.AddSSHConfigEntry(original.SSHHostAlias, original.SSHKeyPath) },
+		})
+	}
+
+	// Keychain secrets are copied now and the old ones deleted once everything succeeded
+	keys := []string{}
+	records, _ := secretStore.List(oldAlias)
+	for _, record := range records {
+		keys = append(keys, record.Key)
+	}
+	if original.AuthMethod == "pat" && !containsString(keys, "pat") {
+		keys = append(keys, "pat")
+	}
+	for _, key := range keys {
+		key := key
+		steps = append(steps, renameStep{
+			desc: fmt.Sprintf("Move keychain secret %s:%s -> %s:%s", oldAlias, key, newAlias, key),
+			do: func() error {
+				value, err := secretStore.Get(oldAlias, key)
+				if errors.Is(err, keychain.ErrSecretNotFound) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				return secretStore.Store(newAlias, key, value)
+			},
+			undo: func() error { return secretStore.Delete(newAlias, key) },
+		})
+	}
+
+	steps = append(steps, renameStep{
+		desc: "Update identities file",
+		do:   func() error { return config.RenameIdentity(oldAlias, renamed) },
+		undo: func() error { return config.RenameIdentity(newAlias, *original) },
+	})
+
+	// Bound repositories
+	for _, repo := range cfg.ReposBoundTo(oldAlias) {
+		dir := repo.Path
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		marker, _ := repoGit(dir, "config", "--local", "--get", "gitx.bound")
+		if marker != oldAlias {
+			continue
+		}
+		remote, _ := repoGit(dir, "remote", "get-url", "origin")
+		newRemote := remote
+		if renamed.SSHHostAlias != original.SSHHostAlias {
+			newRemote = strings.Replace(remote, "@"+original.SSHHostAlias+":", "@"+renamed.SSHHostAlias+":", 1)
+		}
+		desc := fmt.Sprintf("Rebind %s", dir)
+		if newRemote != remote {
+			desc += fmt.Sprintf(" (origin %s)", newRemote)
+		}
+		steps = append(steps, renameStep{
+			desc: desc,
+			do:   func() error { return rebindRepo(dir, newAlias, remote, newRemote) },
+			undo: func() error { return rebindRepo(dir, oldAlias, newRemote, remote) },
+		})
+	}
+
+	return &renamePlan{renamed: &renamed, steps: steps, secretKeys: keys}, nil
+}
+
+// renamedKeyFiles maps the identity's key, public key and certificate files
+// that are named gitx_<alias>... in the gitx key directory to their new names
+func renamedKeyFiles(identity *config.Identity, newAlias string) (map[string]string, error) {
+	moves := map[string]string{}
+	if identity.SSHKeyPath == "" {
+		return moves, nil
+	}
+	defaultKey, err := ssh.KeyPath(identity.Alias)
+	if err != nil {
+		return nil, err
+	}
+	oldPrefix := filepath.Base(defaultKey)
+	dir := filepath.Dir(defaultKey)
+	base := filepath.Base(identity.SSHKeyPath)
+	if filepath.Dir(identity.SSHKeyPath) != dir || !strings.HasPrefix(base, oldPrefix) {
+		return moves, nil
+	}
+	suffix := strings.TrimPrefix(base, oldPrefix)
+	if suffix != "" && !strings.HasPrefix(suffix, "_") {
+		// gitx_work2 belongs to another alias, not to a rotation of gitx_work
+		return moves, nil
+	}
+
+	newKey := filepath.Join(dir, "gitx_"+newAlias+suffix)
+	candidates := []string{identity.SSHKeyPath, identity.SSHKeyPath + ".pub", ssh.CertificatePath(identity.SSHKeyPath)}
+	for _, from := range candidates {
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		to := newKey + strings.TrimPrefix(from, identity.SSHKeyPath)
+		if _, err := os.Stat(to); err == nil {
+			return nil, fmt.Errorf("%s already exists", to)
+		}
+		moves[from] = to
+	}
+	return moves, nil
+}
+
+// rebindRepo sets the gitx.bound marker of the repository at dir and swaps
+// its origin URL from oldRemote to newRemote, changing neither if one fails
+func rebindRepo(dir, alias, oldRemote, newRemote string) error {
+	if newRemote != oldRemote {
+		if _, err := repoGit(dir, "remote", "set-url", "origin", newRemote); err != nil {
+			return err
+		}
+	}
+	if _, err := repoGit(dir, "config", "--local", "gitx.bound", alias); err != nil {
+		if newRemote != oldRemote {
+			repoGit(dir, "remote", "set-url", "origin", oldRemote)
+		}
+		return err
+	}
+	return nil
+}
+
+// repoGit runs git in dir and returns its trimmed output
+func repoGit(dir string, args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return strings.TrimSpace(string(output)), err
+}
+
+// rollbackRename undoes completed steps in reverse order
+func rollbackRename(done []renameStep) {
+	for i := len(done) - 1; i >= 0; i-- {
+		if err := done[i].undo(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Could not undo '"+done[i].desc+"': "+err.Error()))
+		} else {
+			fmt.Println(ui.MutedText.Render("  ↩ " + done[i].desc))
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
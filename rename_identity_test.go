@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+)
+
+func TestRenamedKeyFiles(t *testing.T) {
+	keyDir := t.TempDir()
+	defer func() { ssh.KeyDir = "" }()
+	ssh.KeyDir = keyDir
+
+	for _, name := range []string{"gitx_work", "gitx_work.pub", "gitx_work-cert.pub", "gitx_work2", "gitx_work2.pub"} {
+		os.WriteFile(filepath.Join(keyDir, name), []byte("x"), 0600)
+	}
+
+	moves, err := renamedKeyFiles(&config.Identity{Alias: "work", SSHKeyPath: filepath.Join(keyDir, "gitx_work")}, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		filepath.Join(keyDir, "gitx_work"):          filepath.Join(keyDir, "gitx_job"),
+		filepath.Join(keyDir, "gitx_work.pub"):      filepath.Join(keyDir, "gitx_job.pub"),
+		filepath.Join(keyDir, "gitx_work-cert.pub"): filepath.Join(keyDir, "gitx_job-cert.pub"),
+	}
+	if len(moves) != len(want) {
+		t.Fatalf("moves = %v, want %v", moves, want)
+	}
+	for from, to := range want {
+		if moves[from] != to {
+			t.Errorf("%s -> %s, want %s", from, moves[from], to)
+		}
+	}
+
+	// Another alias's key that happens to share the prefix is left alone
+	moves, _ = renamedKeyFiles(&config.Identity{Alias: "work", SSHKeyPath: filepath.Join(keyDir, "gitx_work2")}, "job")
+	if len(moves) != 0 {
+		t.Errorf("expected no moves for gitx_work2, got %v", moves)
+	}
+
+	// Keys outside the gitx key directory aren't renamed
+	moves, _ = renamedKeyFiles(&config.Identity{Alias: "work", SSHKeyPath: "/elsewhere/id_ed25519"}, "job")
+	if len(moves) != 0 {
+		t.Errorf("expected no moves for an external key, got %v", moves)
+	}
+}
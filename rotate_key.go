@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rotateFinish    bool
+	rotateGraceDays int
+	rotateForce     bool
+	rotateDryRun    bool
+)
+
+func init() {
+	rotateKeyCmd.Flags().BoolVar(&rotateFinish, "finish", false, "Delete the retired key of the last rotation")
+	rotateKeyCmd.Flags().IntVar(&rotateGraceDays, "grace", 14, "Days to keep the previous key before it may be retired")
+	rotateKeyCmd.Flags().BoolVar(&rotateForce, "force", false, "Skip confirmation and allow finishing before the grace period ends")
+	rotateKeyCmd.Flags().BoolVar(&rotateDryRun, "dry-run", false, "Show what would be done without making changes")
+	rootCmd.AddCommand(rotateKeyCmd)
+}
+
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key [alias]",
+	Short: "Rotate the SSH key of an identity",
+	Long: `Generate a new SSH key for an identity and switch the identity and its managed SSH
+config entry over to it. The previous key is kept for a grace period so you can add
+the new key to GitHub first; run 'gitx rotate-key --finish <alias>' to delete it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		if rotateFinish {
+			err = finishKeyRotation(args[0])
+		} else {
+			err = rotateKey(args[0])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func rotateKey(alias string) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	if identity.AuthMethod != "ssh" || identity.SSHKeyPath == "" || identity.SSHHostAlias == "" {
+		return fmt.Errorf("identity '%s' does not use an SSH key", alias)
+	}
+	if identity.ExternalHostEntry {
+		return fmt.Errorf("SSH host %s is not managed by gitx; re-import it with 'gitx import ssh --move' first", identity.SSHHostAlias)
+	}
+	if pending := identity.PendingRotation(); pending != nil {
+		return fmt.Errorf("a previous rotation is still in its grace period; run 'gitx rotate-key --finish %s' first", alias)
+	}
+
+	oldKeyPath := identity.SSHKeyPath
+	oldFingerprint, err := ssh.KeyFingerprint(oldKeyPath)
+	if err != nil {
+		// The old key may already be gone; the rotation is still worth recording
+		oldFingerprint = "(unknown)"
+	}
+
+	if rotateDryRun {
+		fmt.Println("[DRY RUN] Would rotate SSH key:")
+		fmt.Printf("  Old key: %s (%s)\n", oldKeyPath, oldFingerprint)
+		newKeyPath, err := ssh.RotatedKeyPath(alias, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  New key: %s\n", newKeyPath)
+		fmt.Printf("  SSH config: %s -> new key\n", identity.SSHHostAlias)
+		fmt.Printf("  Old key kept until: %s\n", time.Now().AddDate(0, 0, rotateGraceDays).Format("2006-01-02"))
+		return nil
+	}
+
+	now := time.Now()
+	var newKeyPath string
+	if err := ui.SpinnerWithFunc("Generating new SSH key", func() error {
+		var err error
+		newKeyPath, err = ssh.GenerateRotatedSSHKey(alias, now)
+		return err
+	}); err != nil {
+		return err
+	}
+	newFingerprint, err := ssh.KeyFingerprint(newKeyPath)
+	if err != nil {
+		removeKeyFiles(newKeyPath)
+		return err
+	}
+
+	// Switch the SSH config first; if the identity can't be saved afterwards,
+	// point the host entry back at the old key so both stay consistent
+	if err := ui.SpinnerWithFunc("Updating SSH config", func() error {
+		return ssh.AddSSHConfigEntry(identity.SSHHostAlias, newKeyPath)
+	}); err != nil {
+		removeKeyFiles(newKeyPath)
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+
+	// A certificate is bound to the old key and has to be re-signed
+	oldCertPath := identity.SSHCertPath
+	if oldCertPath != "" {
+		if err := ssh.SetCertificateFile(identity.SSHHostAlias, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to remove CertificateFile: "+err.Error()))
+		}
+		identity.SSHCertPath = ""
+	}
+
+	identity.SSHKeyPath = newKeyPath
+	identity.KeyRotations = append(identity.KeyRotations, config.KeyRotation{
+		RotatedAt:      now,
+		OldKeyPath:     oldKeyPath,
+		OldFingerprint: oldFingerprint,
+		NewKeyPath:     newKeyPath,
+		NewFingerprint: newFingerprint,
+		GraceUntil:     now.AddDate(0, 0, rotateGraceDays),
+	})
+	if err := config.UpdateIdentity(*identity); err != nil {
+		rbErr := ssh.AddSSHConfigEntry(identity.SSHHostAlias, oldKeyPath)
+		if rbErr == nil && oldCertPath != "" {
+			rbErr = ssh.SetCertificateFile(identity.SSHHostAlias, oldCertPath)
+		}
+		if rbErr != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to restore SSH config: "+rbErr.Error()))
+		}
+		removeKeyFiles(newKeyPath)
+		return fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	// Load the new key into the isolated agent; the old one stays until --finish
+	if identity.IsolatedAgent {
+		if err := ensureIdentityAgent(identity); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render("⚠️  Warning: failed to load new key into agent: "+err.Error()))
+		}
+	}
+
+	fmt.Println(ui.SuccessText.Render("✓ New SSH key: " + newKeyPath))
+	fmt.Printf("  Old: %s\n", oldFingerprint)
+	fmt.Printf("  New: %s\n", newFingerprint)
+	fmt.Printf("  Previous key kept until %s\n", now.AddDate(0, 0, rotateGraceDays).Format("2006-01-02"))
+
+	if oldCertPath != "" {
+		fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  The certificate %s belongs to the old key; re-sign with: gitx cert sign %s --ca <key>", oldCertPath, alias)))
+	}
+
+	showSSHKeyInstructions(alias, newKeyPath)
+
+	fmt.Printf("Once the new key works, remove the old key from GitHub and run: %sgitx rotate-key --finish %s%s\n", colorBold, alias, colorReset)
+	return nil
+}
+
+func finishKeyRotation(alias string) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+
+	rotation := identity.PendingRotation()
+	if rotation == nil {
+		return fmt.Errorf("identity '%s' has no pending key rotation", alias)
+	}
+	if rotation.OldKeyPath == identity.SSHKeyPath {
+		return fmt.Errorf("retired key %s is still the active key of '%s'", rotation.OldKeyPath, alias)
+	}
+
+	now := time.Now()
+	if now.Before(rotation.GraceUntil) && !rotateForce {
+		return fmt.Errorf("grace period for '%s' ends %s; use --force to retire the old key now",
+			alias, rotation.GraceUntil.Format("2006-01-02"))
+	}
+
+	if rotateDryRun {
+		fmt.Println("[DRY RUN] Would delete retired key:")
+		fmt.Printf("  %s (%s)\n", rotation.OldKeyPath, rotation.OldFingerprint)
+		return nil
+	}
+
+	if !rotateForce {
+		fmt.Printf("Delete retired key %s? (y/n): ", rotation.OldKeyPath)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	if identity.IsolatedAgent {
+		if socketPath, err := identityAgentSocket(alias); err == nil && ssh.AgentRunning(socketPath) {
+			// The key may never have been loaded; nothing to report then
+			_ = ssh.RemoveAgentKey(socketPath, rotation.OldKeyPath)
+		}
+	}
+
+	if err := removeKeyFiles(rotation.OldKeyPath); err != nil {
+		return err
+	}
+
+	rotation.RetiredAt = &now
+	if err := config.UpdateIdentity(*identity); err != nil {
+		return fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Retired key deleted: %s", rotation.OldKeyPath)))
+	return nil
+}
+
+// removeKeyFiles deletes a private key and its .pub, ignoring files that are already gone
+func removeKeyFiles(keyPath string) error {
+	for _, path := range []string{keyPath, keyPath + ".pub"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// secretStore is where commands keep identity secrets; tests swap in a
+// keychain.MemoryStore
+var secretStore keychain.SecretStore = keychain.NewOSStore()
+
+// storeFor returns a throwaway in-memory store for dry runs so they exercise the
+// secret paths without touching the keyring
+func storeFor(dryRun bool) keychain.SecretStore {
+	if dryRun {
+		return keychain.NewMemoryStore()
+	}
+	return secretStore
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsListCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Inspect secrets gitx keeps in the keyring",
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list [alias]",
+	Short: "List the secrets stored for an identity",
+	Long: `List the secrets gitx has stored in the keyring for an identity and when each
+was last set. Values are never shown; only the index kept in the gitx config is read,
+and each entry is checked against the keyring.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listSecrets(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func listSecrets(alias string) error {
+	if _, err := config.FindIdentityByAlias(alias); err != nil {
+		return err
+	}
+	records, err := secretStore.List(alias)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println(ui.InfoBox.Render(fmt.Sprintf("No secrets recorded for '%s'.", alias)))
+		return nil
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+
+	stored, storedErr := secretStore.Stored(alias)
+
+	fmt.Println(ui.HeaderStyle.Render(fmt.Sprintf("🔐 Secrets for '%s'", alias)))
+	for _, record := range records {
+		setAt := "set before gitx tracked it"
+		if !record.UpdatedAt.IsZero() {
+			setAt = "set " + record.UpdatedAt.Local().Format("2006-01-02 15:04")
+		}
+		line := fmt.Sprintf("  %-16s %s", record.Key, ui.MutedText.Render(setAt))
+		if storedErr == nil && !stored[record.Key] {
+			line += "  " + ui.WarningText.Render("(missing from keyring)")
+		}
+		fmt.Println(line)
+	}
+	if storedErr != nil {
+		fmt.Println(ui.WarningText.Render("⚠️  Could not check the keyring: " + storedErr.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFile         string
+	migrateDryRun       bool
+	migrateDeleteBackup bool
+)
+
+func init() {
+	secretsMigrateCmd.Flags().StringVar(&migrateFile, "file", "", "Credential store file to migrate (default: ~/.git-credentials)")
+	secretsMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show what would be migrated without making changes")
+	secretsMigrateCmd.Flags().BoolVar(&migrateDeleteBackup, "delete-backup", false, "Delete the backup of the credential store once the tokens are migrated")
+	secretsCmd.AddCommand(secretsMigrateCmd)
+}
+
+var secretsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move plaintext tokens from ~/.git-credentials into the gitx keyring",
+	Long: `Find entries in ~/.git-credentials that belong to a PAT identity (same host and
+GitHub username), store their tokens in the gitx keyring, remove them from the file
+(a backup is written next to it) and switch the identity's bound repositories to the
+gitx credential helper. The backup still holds the plaintext tokens: gitx offers to
+delete it, or does so without asking with --delete-backup.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := migrateSecrets(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// credentialMatch pairs a credential-store entry with the identity it belongs to
+type credentialMatch struct {
+	entry    keychain.CredentialEntry
+	identity *config.Identity
+}
+
+// matchCredentialEntries assigns store entries to PAT identities by host and
+// username. Entries that fit no identity, or more than one, are returned as notes.
+func matchCredentialEntries(cfg *config.Config, entries []keychain.CredentialEntry) ([]credentialMatch, []string) {
+	var matches []credentialMatch
+	var notes []string
+	for _, entry := range entries {
+		if entry.Protocol != "https" || entry.Username == "" || entry.Password == "" {
+			continue
+		}
+		var candidates []*config.Identity
+		for i := range cfg.Identities {
+			identity := &cfg.Identities[i]
+			if identity.AuthMethod != "pat" || !strings.EqualFold(identity.GitHubUser, entry.Username) {
+				continue
+			}
+			for _, host := range credentialHosts {
+				if entry.Matches(entry.Username, host) {
+					candidates = append(candidates, identity)
+					break
+				}
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			notes = append(notes, fmt.Sprintf("%s: no PAT identity for this user", entry))
+		case 1:
+			matches = append(matches, credentialMatch{entry: entry, identity: candidates[0]})
+		default:
+			notes = append(notes, fmt.Sprintf("%s: matches several identities, left in place", entry))
+		}
+	}
+	return matches, notes
+}
+
+func migrateSecrets() error {
+	file := migrateFile
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		file = filepath.Join(home, ".git-credentials")
+	}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		fmt.Println(ui.InfoBox.Render(fmt.Sprintf("No credential store at %s; nothing to migrate.", file)))
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	matches, notes := matchCredentialEntries(cfg, keychain.ParseCredentialStore(file, string(data)))
+	for _, note := range notes {
+		fmt.Println(ui.MutedText.Render("  - " + note))
+	}
+	if len(matches) == 0 {
+		fmt.Println(ui.InfoBox.Render("No tokens in " + file + " belong to a gitx PAT identity."))
+		return nil
+	}
+
+	if migrateDryRun {
+		fmt.Println("[DRY RUN] Would migrate:")
+	}
+	store := storeFor(migrateDryRun)
+	var migratedLines []string
+	migrated := map[string]bool{}
+	for _, match := range matches {
+		alias := match.identity.Alias
+		if migrated[alias] {
+			// git only ever uses the first entry for a host/user, so later ones are stale
+			fmt.Printf("  %s %s → '%s' %s\n", ui.WarningText.Render("!"), match.entry, alias, ui.MutedText.Render("(duplicate, removed without storing)"))
+			migratedLines = append(migratedLines, match.entry.Line)
+			continue
+		}
+		if existing, err := secretStore.Get(alias, "pat"); err == nil && existing != match.entry.Password {
+			fmt.Printf("  %s '%s' already has a different PAT in the keyring; replacing it with the one git has been using\n", ui.WarningText.Render("!"), alias)
+		}
+		if err := store.Store(alias, "pat", match.entry.Password); err != nil {
+			return fmt.Errorf("failed to store PAT for '%s': %w", alias, err)
+		}
+		migrated[alias] = true
+		migratedLines = append(migratedLines, match.entry.Line)
+		fmt.Printf("  %s %s → '%s'\n", ui.SuccessText.Render("✓"), match.entry, alias)
+	}
+
+	// Repositories that should stop using the store helper
+	var repos []config.BoundRepo
+	for alias := range migrated {
+		repos = append(repos, cfg.ReposBoundTo(alias)...)
+	}
+
+	if migrateDryRun {
+		for _, repo := range repos {
+			fmt.Printf("  credential.helper in %s → gitx\n", repo.Path)
+		}
+		fmt.Printf("  %d line(s) would be removed from %s\n", len(migratedLines), file)
+		return nil
+	}
+
+	backup, err := keychain.RemoveStoreLines(file, migratedLines)
+	if err != nil {
+		return err
+	}
+	fmt.Println(ui.SuccessText.Render(fmt.Sprintf("✓ Removed %d line(s) from %s (backup: %s)", len(migratedLines), file, backup)))
+
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.Path); err != nil {
+			fmt.Println(ui.MutedText.Render("  - " + repo.Path + ": no longer exists, skipped"))
+			continue
+		}
+		if err := useGitxCredentialHelper(repo.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", ui.WarningText.Render(fmt.Sprintf("⚠️  %s: %v", repo.Path, err)))
+			continue
+		}
+		fmt.Printf("  %s %s now uses the gitx credential helper\n", ui.SuccessText.Render("✓"), repo.Path)
+	}
+
+	deleteBackup := migrateDeleteBackup
+	if !deleteBackup && stdinIsTerminal() {
+		fmt.Printf("The backup %s still contains the plaintext tokens. Delete it? (y/n) [n]: ", backup)
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		deleteBackup = response == "y" || response == "yes"
+	}
+	if deleteBackup {
+		if err := os.Remove(backup); err != nil {
+			return fmt.Errorf("failed to delete backup: %w", err)
+		}
+		fmt.Println(ui.SuccessText.Render("✓ Deleted " + backup))
+		return nil
+	}
+	fmt.Println(ui.WarningText.Render(fmt.Sprintf("⚠️  %s contains the migrated tokens in plaintext; delete it once you've checked the migration", backup)))
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/keychain"
 	"github.com/csawai/git-identity-switcher/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -116,10 +117,47 @@ func showStatus() error {
 		statusText,
 	)
 
+	if boundIdentity != "" {
+		if cfg, err := config.LoadConfig(); err == nil {
+			for i := range cfg.Identities {
+				identity := &cfg.Identities[i]
+				if identity.Alias != boundIdentity {
+					continue
+				}
+				for _, warning := range []string{emailWarning(identity, email), certificateWarning(identity), patExpiryWarning(cfg, identity)} {
+					if warning != "" {
+						content += "\n" + ui.WarningText.Render("⚠️  "+warning)
+					}
+				}
+			}
+		}
+	}
+
+	if secrets, err := repoConfigSecrets(); err == nil && len(secrets) > 0 {
+		content += "\n" + ui.ErrorText.Render(fmt.Sprintf("⚠️  %d credential(s) in .git/config (run 'gitx audit secrets')", len(secrets)))
+		for _, secret := range secrets {
+			content += "\n   " + ui.MutedText.Render(secret.Masked)
+		}
+	}
+
+	if backend, err := keychain.ActiveBackend(); err == nil {
+		content += "\n🔐 Keyring: " + ui.MutedText.Render(backend)
+	} else {
+		content += "\n" + ui.WarningText.Render("⚠️  Keyring unavailable: "+err.Error())
+	}
+
 	fmt.Println(boxStyle.Render(content))
 	return nil
 }
 
+// emailWarning returns a message when email isn't one the identity commits with
+func emailWarning(identity *config.Identity, email string) string {
+	if identity.AllowsEmail(email) {
+		return ""
+	}
+	return fmt.Sprintf("user.email %s is not one of %s's emails (run 'gitx bind %s')", email, identity.Alias, identity.Alias)
+}
+
 func isGitRepo() bool {
 	cmd := exec.Command("git", "rev-parse", "--git-dir")
 	return cmd.Run() == nil
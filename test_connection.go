@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/csawai/git-identity-switcher/internal/config"
+	"github.com/csawai/git-identity-switcher/internal/ssh"
+	"github.com/csawai/git-identity-switcher/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var testSSHCommand string
+
+func init() {
+	testCmd.Flags().StringVar(&testSSHCommand, "ssh-command", "", "SSH command to use (default $GITX_SSH_COMMAND or ssh)")
+	rootCmd.AddCommand(testCmd)
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test [alias]",
+	Short: "Test an identity's SSH connection to GitHub",
+	Long: `Run 'ssh -T git@<host-alias>' for an identity and check that the account GitHub
+authenticates the key as matches the identity's GitHub username.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := testConnection(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func testConnection(alias string) error {
+	identity, err := config.FindIdentityByAlias(alias)
+	if err != nil {
+		return err
+	}
+	if identity.AuthMethod != "ssh" || identity.SSHHostAlias == "" {
+		return fmt.Errorf("identity '%s' does not use SSH; only SSH identities can be tested", alias)
+	}
+
+	sshCommand := testSSHCommand
+	if sshCommand == "" {
+		sshCommand = os.Getenv("GITX_SSH_COMMAND")
+	}
+
+	var result *ssh.ConnectionResult
+	if err := ui.SpinnerWithFunc(fmt.Sprintf("Connecting to git@%s", identity.SSHHostAlias), func() error {
+		var err error
+		result, err = ssh.CheckConnection(sshCommand, identity.SSHHostAlias)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if !result.Authenticated {
+		content := fmt.Sprintf("❌ Authentication failed for '%s'\n\n%s", alias, ui.MutedText.Render(result.Output))
+		if strings.Contains(result.Output, "Permission denied") {
+			content += "\n\n" + "The key was not accepted. Add it to GitHub with: gitx show-key " + alias
+		}
+		fmt.Println(ui.ErrorBox.Render(content))
+		return fmt.Errorf("could not authenticate as %s", identity.GitHubUser)
+	}
+
+	if !strings.EqualFold(result.User, identity.GitHubUser) {
+		fmt.Println(ui.ErrorBox.Render(fmt.Sprintf(
+			"❌ This key belongs to a different account\n\nExpected: %s\nGitHub says: %s\n\nRemove the key from %s's account and add it to %s's instead.",
+			identity.GitHubUser, result.User, result.User, identity.GitHubUser)))
+		return fmt.Errorf("key for '%s' authenticates as %s, not %s", alias, result.User, identity.GitHubUser)
+	}
+
+	fmt.Println(ui.SuccessBox.Render(fmt.Sprintf("✅ '%s' authenticates as %s", alias, result.User)))
+	return nil
+}
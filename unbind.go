@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/csawai/git-identity-switcher/internal/config"
 	"github.com/csawai/git-identity-switcher/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -57,6 +58,18 @@ func unbind() error {
 	// Remove gitx binding marker
 	unsetMarker := exec.Command("git", "config", "--local", "--unset", "gitx.bound")
 	_ = unsetMarker.Run() // Ignore error if not set
+	exec.Command("git", "config", "--local", "--unset-all", "gitx.email").Run()
+	if err := revertGitConfigOverrides(nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	removeGitxCredentialHelper()
+
+	if topLevel, err := repoTopLevel(); err == nil {
+		if err := config.ForgetBinding(topLevel); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not update bound repositories: %v\n", err)
+		}
+	}
 
 	// Try to revert remote URL to standard github.com format
 	cmd := exec.Command("git", "remote", "get-url", "origin")
@@ -82,3 +95,18 @@ func unbind() error {
 	fmt.Println(ui.SuccessBox.Render("✅ Repository unbound successfully"))
 	return nil
 }
+
+// removeGitxCredentialHelper drops the gitx credential helper (and the reset
+// entry in front of it) from the current repository
+func removeGitxCredentialHelper() {
+	output, err := exec.Command("git", "config", "--local", "--get-all", "credential.helper").Output()
+	if err != nil {
+		return
+	}
+	for _, helper := range strings.Split(string(output), "\n") {
+		if isGitxCredentialHelper(strings.TrimSpace(helper)) {
+			exec.Command("git", "config", "--local", "--unset-all", "credential.helper").Run()
+			return
+		}
+	}
+}